@@ -11,6 +11,7 @@ import SettingsView from '../settings/SettingsView';
 import { Profile } from '../profile/Profile'; 
 import { CreateChannelModal } from '../../components/CreateChannelModal'; 
 import CreateServerModal from '../../components/CreateServerModal'; 
+import { GatewayClient } from '../../services/gateway';
 
 export default function DashboardLayout({ section }) {
   const navigate = useNavigate();
@@ -28,7 +29,8 @@ export default function DashboardLayout({ section }) {
   const [messagesError, setMessagesError] = useState(null);
   
   const [newMessage, setNewMessage] = useState('');
-  const socketRef = useRef(null);
+  const gatewayRef = useRef(null);
+  const joinedChannelRef = useRef(null); // Canal cujos eventos são exibidos no chat
   const chatContainerRef = useRef(null); // Ref para o container de mensagens do chat
 
   const [selectedServerId, setSelectedServerId] = useState(null);
@@ -136,7 +138,7 @@ export default function DashboardLayout({ section }) {
         throw new Error(errData.error || `Erro ao buscar mensagens: ${response.status}`);
       }
       const data = await response.json();
-      setMessages(Array.isArray(data) ? data.reverse() : []);
+      setMessages(Array.isArray(data.messages) ? data.messages.reverse() : []);
     } catch (error) {
       console.error(`Erro ao buscar mensagens para canal ${currentChannelId}:`, error);
       setMessagesError(error.message || "Não foi possível carregar as mensagens.");
@@ -173,48 +175,64 @@ export default function DashboardLayout({ section }) {
     };
   }, [contextMenu]);
 
+  // Conexão com o gateway, mantida enquanto o dashboard estiver aberto
+  useEffect(() => {
+    const gateway = new GatewayClient({
+      onReady: () => {
+        // Refaz a inscrição do canal aberto (uma sessão nova não tem inscrições)
+        if (joinedChannelRef.current) {
+          gateway.send('join_channel', { channelId: joinedChannelRef.current });
+        }
+      },
+      onDispatch: (t, d) => {
+        switch (t) {
+          case 'message_create':
+            if (d.channelId === joinedChannelRef.current) {
+              setMessages((prevMessages) => [...prevMessages, d]);
+            }
+            break;
+          case 'message_update':
+            if (d.channelId === joinedChannelRef.current) {
+              setMessages((prevMessages) => prevMessages.map((msg) => (msg.id === d.id ? d : msg)));
+            }
+            break;
+          case 'message_delete':
+            if (d.channelId === joinedChannelRef.current) {
+              setMessages((prevMessages) => prevMessages.filter((msg) => msg.id !== d.id));
+            }
+            break;
+          case 'error':
+            console.error('[Gateway] Erro:', d.message);
+            setChatError(d.message);
+            break;
+          default:
+            break;
+        }
+      },
+    });
+    gatewayRef.current = gateway;
+    gateway.connect();
+    return () => {
+      gateway.close();
+      gatewayRef.current = null;
+    };
+  }, []);
+
   useEffect(() => {
     if (view === 'channel' && selectedChannelId) {
       const currentChannel = channels.find(c => c.ID === selectedChannelId);
       if (currentChannel && currentChannel.ChannelType === 'TEXT') {
         fetchMessagesForChannel(selectedChannelId);
-        const token = localStorage.getItem('authToken');
-        if (!token) { console.error("[WebSocket] Token não encontrado."); return; }
-        
-        const wsUrl = `ws://localhost:5000/ws/chat`;
-        if (socketRef.current) socketRef.current.close();
-        socketRef.current = new WebSocket(wsUrl);
-        socketRef.current.onopen = () => {
-          socketRef.current.send(JSON.stringify({
-            type: 'join_channel',
-            payload: { channelId: selectedChannelId, token: token }
-          }));
-        };
-        socketRef.current.onmessage = (event) => {
-          try {
-            const messageData = JSON.parse(event.data);
-            if (messageData.channelId === selectedChannelId) {
-              setMessages((prevMessages) => [...prevMessages, messageData]);
-            }
-          } catch (e) { console.error('[WebSocket] Erro ao parsear mensagem:', e); }
-        };
-        socketRef.current.onerror = (error) => console.error('[WebSocket] Erro:', error);
-        socketRef.current.onclose = (event) => {
-          console.log(`[WebSocket] Desconectado. Code: ${event.code}, Reason: ${event.reason}`);
-          socketRef.current = null;
-        };
+        joinedChannelRef.current = selectedChannelId;
+        // Se a sessão ainda não estiver pronta, a inscrição é feita em onReady
+        if (gatewayRef.current) {
+          gatewayRef.current.send('join_channel', { channelId: selectedChannelId });
+        }
         return () => {
-          if (socketRef.current) {
-            socketRef.current.send(JSON.stringify({ type: 'leave_channel', payload: { channelId: selectedChannelId } }));
-            socketRef.current.close();
-            socketRef.current = null;
-          }
+          joinedChannelRef.current = null;
         };
       } else {
-        if (socketRef.current) {
-          socketRef.current.close();
-          socketRef.current = null;
-        }
+        joinedChannelRef.current = null;
         setMessages([]);
       }
     }
@@ -288,7 +306,7 @@ export default function DashboardLayout({ section }) {
     const token = localStorage.getItem('authToken');
     if (!token) { alert('Autenticação necessária.'); return; }
     try {
-      const response = await fetch(`http://localhost:5000/servers/${serverIdToLeave}/members/me`, {
+      const response = await fetch(`http://localhost:5000/users/me/servers/${serverIdToLeave}`, {
         method: 'DELETE', headers: { 'Authorization': `Bearer ${token}` },
       });
       if (!response.ok) {
@@ -306,17 +324,13 @@ export default function DashboardLayout({ section }) {
     setChatError('');
     if (!newMessage.trim()) { return; }
     if (!selectedChannelId) { setChatError("Nenhum canal selecionado."); return; }
-    if (!socketRef.current) { setChatError('Não conectado ao chat.'); return; }
-    if (socketRef.current.readyState !== WebSocket.OPEN) {
-      setChatError(`Conexão com o chat não está pronta (Estado: ${socketRef.current.readyState}).`);
-      return;
-    }
-    const messagePayload = {
-      type: 'new_message',
-      payload: { channelId: selectedChannelId, content: newMessage.trim() }
-    };
+    if (!gatewayRef.current) { setChatError('Não conectado ao chat.'); return; }
     try {
-      socketRef.current.send(JSON.stringify(messagePayload));
+      const sent = gatewayRef.current.send('new_message', { channelId: selectedChannelId, content: newMessage.trim() });
+      if (!sent) {
+        setChatError('Conexão com o chat não está pronta.');
+        return;
+      }
       setNewMessage('');
     } catch (sendError) {
       console.error('[handleSendMessage] ERRO ao tentar enviar mensagem via WebSocket:', sendError);
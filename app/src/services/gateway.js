@@ -0,0 +1,195 @@
+// Cliente do gateway de tempo real (/ws/chat?v=1).
+//
+// O upgrade é autenticado com um ticket de uso único (POST /ws/ticket). Após o
+// HELLO o cliente envia heartbeats no intervalo informado e IDENTIFY para abrir
+// uma sessão; ao reconectar, envia RESUME com o session_id e a última sequência
+// recebida para receber os eventos perdidos.
+
+const API_URL = 'http://localhost:5000';
+const GATEWAY_URL = 'ws://localhost:5000/ws/chat';
+const GATEWAY_VERSION = 1;
+
+export const Op = {
+  DISPATCH: 0,
+  HEARTBEAT: 1,
+  IDENTIFY: 2,
+  COMMAND: 3,
+  RESUME: 6,
+  INVALID_SESSION: 9,
+  HELLO: 10,
+  HEARTBEAT_ACK: 11,
+};
+
+const MAX_RECONNECT_DELAY = 30000;
+
+export class GatewayClient {
+  // onDispatch(t, d) recebe os eventos (op 0); onReady() é chamado quando a
+  // sessão fica pronta (ready ou resumed), para refazer as inscrições em canais.
+  constructor({ onDispatch, onReady } = {}) {
+    this.onDispatch = onDispatch || (() => {});
+    this.onReady = onReady || (() => {});
+    this.socket = null;
+    this.sessionId = null;
+    this.seq = 0;
+    this.ready = false;
+    this.closed = false;
+    this.heartbeatTimer = null;
+    this.heartbeatAcked = true;
+    this.reconnectTimer = null;
+    this.reconnectAttempts = 0;
+  }
+
+  async connect() {
+    this.closed = false;
+    const token = localStorage.getItem('authToken');
+    if (!token) {
+      console.error('[Gateway] Token não encontrado.');
+      return;
+    }
+
+    let ticket;
+    try {
+      const response = await fetch(`${API_URL}/ws/ticket`, {
+        method: 'POST',
+        headers: { 'Authorization': `Bearer ${token}` },
+      });
+      if (!response.ok) {
+        const errData = await response.json().catch(() => ({ error: "Resposta de erro não é JSON" }));
+        throw new Error(errData.error || `Erro ao obter ticket: ${response.status}`);
+      }
+      ({ ticket } = await response.json());
+    } catch (error) {
+      console.error('[Gateway] Falha ao obter ticket:', error);
+      this.scheduleReconnect();
+      return;
+    }
+    if (this.closed) return;
+
+    const socket = new WebSocket(`${GATEWAY_URL}?v=${GATEWAY_VERSION}&ticket=${encodeURIComponent(ticket)}`);
+    this.socket = socket;
+    socket.onmessage = (event) => this.handleFrame(event.data);
+    socket.onerror = (error) => console.error('[Gateway] Erro:', error);
+    socket.onclose = (event) => {
+      console.log(`[Gateway] Desconectado. Code: ${event.code}, Reason: ${event.reason}`);
+      this.handleDisconnect(socket);
+    };
+  }
+
+  // handleDisconnect descarta a conexão atual e agenda a reconexão; a sessão é
+  // mantida para o RESUME.
+  handleDisconnect(socket) {
+    if (this.socket !== socket) return;
+    this.socket = null;
+    this.ready = false;
+    this.stopHeartbeat();
+    this.scheduleReconnect();
+  }
+
+  // close encerra a conexão sem reconectar e descarta a sessão.
+  close() {
+    this.closed = true;
+    this.ready = false;
+    this.sessionId = null;
+    this.seq = 0;
+    this.stopHeartbeat();
+    clearTimeout(this.reconnectTimer);
+    if (this.socket) {
+      const socket = this.socket;
+      this.socket = null;
+      socket.close();
+    }
+  }
+
+  // send envia um comando (op 3). Retorna false se a sessão ainda não está pronta.
+  send(t, d) {
+    if (!this.ready || !this.socket || this.socket.readyState !== WebSocket.OPEN) {
+      return false;
+    }
+    this.sendFrame({ op: Op.COMMAND, t, d });
+    return true;
+  }
+
+  sendFrame(frame) {
+    if (this.socket && this.socket.readyState === WebSocket.OPEN) {
+      this.socket.send(JSON.stringify(frame));
+    }
+  }
+
+  handleFrame(data) {
+    let frame;
+    try {
+      frame = JSON.parse(data);
+    } catch (e) {
+      console.error('[Gateway] Erro ao parsear frame:', e);
+      return;
+    }
+
+    switch (frame.op) {
+      case Op.HELLO:
+        this.startHeartbeat(frame.d.heartbeatInterval);
+        if (this.sessionId) {
+          this.sendFrame({ op: Op.RESUME, d: { sessionId: this.sessionId, seq: this.seq } });
+        } else {
+          this.sendFrame({ op: Op.IDENTIFY });
+        }
+        break;
+
+      case Op.HEARTBEAT_ACK:
+        this.heartbeatAcked = true;
+        break;
+
+      case Op.INVALID_SESSION:
+        // A sessão expirou ou saiu do buffer do servidor: começa uma nova.
+        this.sessionId = null;
+        this.seq = 0;
+        this.sendFrame({ op: Op.IDENTIFY });
+        break;
+
+      case Op.DISPATCH:
+        if (frame.s) this.seq = frame.s;
+        if (frame.t === 'ready' || frame.t === 'resumed') {
+          if (frame.t === 'ready') this.sessionId = frame.d.sessionId;
+          this.ready = true;
+          this.reconnectAttempts = 0;
+          this.onReady();
+        }
+        this.onDispatch(frame.t, frame.d);
+        break;
+
+      default:
+        console.warn('[Gateway] Opcode desconhecido:', frame.op);
+    }
+  }
+
+  startHeartbeat(interval) {
+    this.stopHeartbeat();
+    this.heartbeatAcked = true;
+    this.heartbeatTimer = setInterval(() => {
+      if (!this.heartbeatAcked) {
+        // Sem ACK do heartbeat anterior: a conexão é tratada como zumbi e reaberta.
+        console.warn('[Gateway] Heartbeat sem resposta; reconectando.');
+        const socket = this.socket;
+        if (socket) {
+          socket.close();
+          this.handleDisconnect(socket);
+        }
+        return;
+      }
+      this.heartbeatAcked = false;
+      this.sendFrame({ op: Op.HEARTBEAT, d: this.seq });
+    }, interval);
+  }
+
+  stopHeartbeat() {
+    clearInterval(this.heartbeatTimer);
+    this.heartbeatTimer = null;
+  }
+
+  scheduleReconnect() {
+    if (this.closed) return;
+    const delay = Math.min(1000 * 2 ** this.reconnectAttempts, MAX_RECONNECT_DELAY);
+    this.reconnectAttempts += 1;
+    clearTimeout(this.reconnectTimer);
+    this.reconnectTimer = setTimeout(() => this.connect(), delay);
+  }
+}
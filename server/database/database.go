@@ -57,6 +57,14 @@ func AutoMigrateModels() {
 		return // ou panic("DB is nil")
 	}
 
+	// server_members guarda os dados do membro no servidor (apelido, avatar, entrada)
+	if err := DB.SetupJoinTable(&models.Server{}, "Members", &models.ServerMember{}); err != nil {
+		log.Fatalf("Falha ao configurar a tabela server_members: %v", err)
+	}
+	if err := DB.SetupJoinTable(&models.User{}, "MemberServers", &models.ServerMember{}); err != nil {
+		log.Fatalf("Falha ao configurar a tabela server_members: %v", err)
+	}
+
 	// Certifique-se de que seus modelos estão corretos e o pacote models foi importado.
 	// O caminho "github.com/gustavoverneck/discordia/server/models" deve estar correto.
 	err := DB.AutoMigrate(
@@ -64,12 +72,26 @@ func AutoMigrateModels() {
 		&models.Server{},
 		&models.Channel{},
 		&models.Message{},
+		&models.MessageEdit{},
+		&models.MessageMention{},
+		&models.Attachment{},
+		&models.ServerMember{},
+		&models.Invite{},
+		&models.Ban{},
+		&models.AuditLogEntry{},
+		&models.Role{},
+		&models.MemberRole{},
+		&models.PermissionOverwrite{},
+		&models.Relationship{},
+		&models.ReadState{},
 		// Adicione quaisquer outros modelos que você tenha definido aqui
-		// ex: &models.ServerMember{}, se você tiver uma tabela de junção explícita.
 	)
 
 	if err != nil {
 		log.Fatalf("Falha ao executar AutoMigrate: %v", err)
 	}
+
+	// Índice de busca textual das mensagens (depende da tabela messages)
+	SetupMessageSearch(DB)
 	fmt.Println("Migração do banco de dados concluída.")
 }
@@ -0,0 +1,83 @@
+package database
+
+import (
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// MessageSearchTable é a tabela FTS5 que indexa o conteúdo das mensagens.
+const MessageSearchTable = "messages_fts"
+
+// messageSearchTriggers mantêm o índice sincronizado com a tabela messages.
+// Mensagens apagadas (soft delete) saem do índice e voltam se forem restauradas.
+var messageSearchTriggers = []string{
+	`CREATE TRIGGER IF NOT EXISTS messages_fts_ai AFTER INSERT ON messages
+	WHEN new.deleted_at IS NULL BEGIN
+		INSERT INTO messages_fts(rowid, content) VALUES (new.id, new.content);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS messages_fts_au AFTER UPDATE OF content, deleted_at ON messages BEGIN
+		INSERT INTO messages_fts(messages_fts, rowid, content)
+			SELECT 'delete', old.id, old.content WHERE old.deleted_at IS NULL;
+		INSERT INTO messages_fts(rowid, content)
+			SELECT new.id, new.content WHERE new.deleted_at IS NULL;
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS messages_fts_ad AFTER DELETE ON messages
+	WHEN old.deleted_at IS NULL BEGIN
+		INSERT INTO messages_fts(messages_fts, rowid, content) VALUES ('delete', old.id, old.content);
+	END`,
+}
+
+// SetupMessageSearch cria o índice FTS5 das mensagens e os triggers que o
+// mantêm atualizado. O SQLite precisa ter sido compilado com FTS5
+// (go build -tags sqlite_fts5); caso contrário a busca usa LIKE como alternativa.
+func SetupMessageSearch(db *gorm.DB) {
+	if db.Migrator().HasTable(MessageSearchTable) && !MessageSearchAvailable(db) {
+		// O índice foi criado por um binário com FTS5, mas este não tem o módulo:
+		// sem os triggers, inserir ou editar mensagens voltaria a funcionar.
+		for _, name := range []string{"messages_fts_ai", "messages_fts_au", "messages_fts_ad"} {
+			if err := db.Exec("DROP TRIGGER IF EXISTS " + name).Error; err != nil {
+				log.Printf("Erro ao remover trigger %s: %v", name, err)
+			}
+		}
+		log.Printf("Busca FTS5 indisponível neste binário, usando busca simples por LIKE.")
+		return
+	}
+
+	var triggers int64
+	db.Raw("SELECT count(*) FROM sqlite_master WHERE type = 'trigger' AND name LIKE 'messages_fts_%'").Scan(&triggers)
+	if db.Migrator().HasTable(MessageSearchTable) && triggers == int64(len(messageSearchTriggers)) {
+		return // Índice pronto e sincronizado
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`CREATE VIRTUAL TABLE IF NOT EXISTS messages_fts USING fts5(
+			content, content='messages', content_rowid='id', tokenize='unicode61 remove_diacritics 2')`).Error; err != nil {
+			return err
+		}
+		for _, trigger := range messageSearchTriggers {
+			if err := tx.Exec(trigger).Error; err != nil {
+				return err
+			}
+		}
+		// (Re)indexa as mensagens existentes; o índice pode estar desatualizado se
+		// o servidor rodou sem FTS5 por um tempo.
+		if err := tx.Exec(`INSERT INTO messages_fts(messages_fts) VALUES ('delete-all')`).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`INSERT INTO messages_fts(rowid, content)
+			SELECT id, content FROM messages WHERE deleted_at IS NULL`).Error
+	})
+	if err != nil {
+		log.Printf("Busca FTS5 indisponível, usando busca simples por LIKE: %v", err)
+	}
+}
+
+// MessageSearchAvailable informa se o índice FTS5 das mensagens existe e pode
+// ser consultado por este binário.
+func MessageSearchAvailable(db *gorm.DB) bool {
+	if !db.Migrator().HasTable(MessageSearchTable) {
+		return false
+	}
+	return db.Exec("SELECT rowid FROM messages_fts LIMIT 0").Error == nil
+}
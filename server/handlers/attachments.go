@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	_ "image/gif" // Registra os decodificadores usados para ler as dimensões das imagens
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/gustavoverneck/discordia/server/imaging"
+	"github.com/gustavoverneck/discordia/server/models"
+	"github.com/gustavoverneck/discordia/server/storage"
+)
+
+const (
+	maxAttachmentSize        = 25 * 1024 * 1024 // Tamanho máximo de cada anexo
+	maxAttachmentsTotalSize  = 50 * 1024 * 1024 // Soma máxima dos anexos de uma mensagem
+	maxAttachmentsPerMessage = 10
+	maxAttachmentNameLength  = 255
+	attachmentsKeyPrefix     = "attachments"
+)
+
+var (
+	errTooManyAttachments = errors.New("uma mensagem pode ter no máximo 10 anexos")
+	errAttachmentTooLarge = errors.New("cada anexo pode ter no máximo 25MB e a mensagem, 50MB no total")
+	errAttachmentType     = errors.New("tipo de arquivo não permitido")
+)
+
+// attachmentExtensions mapeia os tipos aceitos (detectados pelo conteúdo, não
+// pelo nome enviado) para a extensão usada no arquivo salvo. HTML e SVG são
+// guardados como texto simples, para que o navegador nunca os execute.
+var attachmentExtensions = map[string]string{
+	"image/png":                    ".png",
+	"image/jpeg":                   ".jpg",
+	"image/gif":                    ".gif",
+	"image/webp":                   ".webp",
+	"image/bmp":                    ".bmp",
+	"text/plain":                   ".txt",
+	"application/pdf":              ".pdf",
+	"application/zip":              ".zip",
+	"application/x-gzip":           ".gz",
+	"application/x-rar-compressed": ".rar",
+}
+
+// opaqueAttachmentExtensions são aceitas quando o conteúdo não é reconhecido
+// (application/octet-stream), ex: formatos de arquivo compactado sem assinatura detectável.
+var opaqueAttachmentExtensions = map[string]bool{
+	".7z":  true,
+	".tar": true,
+	".bz2": true,
+	".xz":  true,
+}
+
+type AttachmentResponse struct {
+	ID          uint   `json:"id"`
+	Filename    string `json:"filename"` // Nome original enviado pelo usuário
+	URL         string `json:"url"`
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size"`
+	Width       *int   `json:"width,omitempty"` // Apenas imagens
+	Height      *int   `json:"height,omitempty"`
+}
+
+func toAttachmentResponse(attachment models.Attachment) AttachmentResponse {
+	return AttachmentResponse{
+		ID:          attachment.ID,
+		Filename:    attachment.Filename,
+		URL:         attachment.URL,
+		ContentType: attachment.ContentType,
+		Size:        attachment.Size,
+		Width:       attachment.Width,
+		Height:      attachment.Height,
+	}
+}
+
+// sanitizeAttachmentName mantém apenas o nome-base do arquivo enviado, sem caminhos.
+func sanitizeAttachmentName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" || strings.TrimSpace(name) == "" {
+		name = "arquivo"
+	}
+	if runes := []rune(name); len(runes) > maxAttachmentNameLength {
+		name = string(runes[len(runes)-maxAttachmentNameLength:])
+	}
+	return name
+}
+
+// validateAttachments confere a quantidade e o tamanho dos anexos antes de salvá-los.
+func validateAttachments(files []*multipart.FileHeader) error {
+	if len(files) > maxAttachmentsPerMessage {
+		return errTooManyAttachments
+	}
+	var total int64
+	for _, fileHeader := range files {
+		if fileHeader.Size > maxAttachmentSize {
+			return errAttachmentTooLarge
+		}
+		total += fileHeader.Size
+	}
+	if total > maxAttachmentsTotalSize {
+		return errAttachmentTooLarge
+	}
+	return nil
+}
+
+// saveAttachment detecta o tipo do arquivo pelo conteúdo, grava-o no backend
+// com um nome UUID e retorna seus metadados (ainda sem MessageID).
+func saveAttachment(ctx context.Context, store storage.Storage, fileHeader *multipart.FileHeader) (models.Attachment, error) {
+	file, err := fileHeader.Open()
+	if err != nil {
+		return models.Attachment{}, err
+	}
+	defer file.Close()
+
+	buffer := make([]byte, 512)
+	n, err := io.ReadFull(file, buffer)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return models.Attachment{}, err
+	}
+	buffer = buffer[:n]
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return models.Attachment{}, err
+	}
+
+	filename := sanitizeAttachmentName(fileHeader.Filename)
+	contentType := detectContentType(buffer)
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	ext, ok := attachmentExtensions[mediaType]
+	if !ok {
+		ext = strings.ToLower(filepath.Ext(filename))
+		if mediaType != "application/octet-stream" || !opaqueAttachmentExtensions[ext] {
+			return models.Attachment{}, errAttachmentType
+		}
+	}
+
+	attachment := models.Attachment{
+		Filename:    filename,
+		ContentType: contentType,
+		Size:        fileHeader.Size,
+	}
+	var body io.Reader = file
+	if strings.HasPrefix(mediaType, "image/") {
+		// Remove EXIF (inclusive localização GPS) e outros metadados antes de publicar a imagem
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return models.Attachment{}, err
+		}
+		data = imaging.StripMetadata(data, mediaType)
+		body, attachment.Size = bytes.NewReader(data), int64(len(data))
+		// Formatos sem decodificador na biblioteca padrão (webp, bmp) ficam sem dimensões
+		if config, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+			attachment.Width, attachment.Height = &config.Width, &config.Height
+		}
+	}
+
+	url, err := storeUpload(ctx, store, attachmentsKeyPrefix, ext, body, attachment.Size, contentType)
+	if err != nil {
+		return models.Attachment{}, err
+	}
+	attachment.URL = url
+	return attachment, nil
+}
+
+// detectContentType é http.DetectContentType, mas trata arquivos de texto com
+// marcação (HTML, XML/SVG) como texto simples, que é como serão servidos.
+func detectContentType(data []byte) string {
+	contentType := http.DetectContentType(data)
+	if strings.HasPrefix(contentType, "text/html") || strings.HasPrefix(contentType, "text/xml") {
+		return "text/plain; charset=utf-8"
+	}
+	return contentType
+}
+
+// saveAttachments salva todos os arquivos; se algum falhar, remove os já salvos.
+func saveAttachments(ctx context.Context, store storage.Storage, files []*multipart.FileHeader) ([]models.Attachment, error) {
+	if err := validateAttachments(files); err != nil {
+		return nil, err
+	}
+	attachments := make([]models.Attachment, 0, len(files))
+	for _, fileHeader := range files {
+		attachment, err := saveAttachment(ctx, store, fileHeader)
+		if err != nil {
+			removeAttachmentFiles(ctx, store, attachments)
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments, nil
+}
+
+// removeAttachmentFiles apaga os arquivos de anexos que não chegaram a ser
+// associados a uma mensagem ou cujos registros foram apagados.
+func removeAttachmentFiles(ctx context.Context, store storage.Storage, attachments []models.Attachment) {
+	for _, attachment := range attachments {
+		deleteUpload(ctx, store, attachment.URL)
+	}
+}
@@ -0,0 +1,373 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gustavoverneck/discordia/server/models"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultAuditLogLimit = 50
+	maxAuditLogLimit     = 100
+	maxAuditReasonLength = 512
+
+	// auditReasonHeader traz o motivo (codificado como URL) das ações sem campo próprio para ele.
+	auditReasonHeader = "X-Audit-Log-Reason"
+)
+
+// Tipos de ação do registro de auditoria.
+const (
+	AuditServerUpdate        = "server_update"
+	AuditServerOwnerTransfer = "server_owner_transfer"
+	AuditChannelCreate       = "channel_create"
+	AuditChannelUpdate       = "channel_update"
+	AuditChannelDelete       = "channel_delete"
+	AuditOverwriteUpdate     = "channel_overwrite_update"
+	AuditOverwriteDelete     = "channel_overwrite_delete"
+	AuditRoleCreate          = "role_create"
+	AuditRoleUpdate          = "role_update"
+	AuditRoleDelete          = "role_delete"
+	AuditMemberRoleAdd       = "member_role_add"
+	AuditMemberRoleRemove    = "member_role_remove"
+	AuditMemberUpdate        = "member_update"
+	AuditMemberKick          = "member_kick"
+	AuditMemberBanAdd        = "member_ban_add"
+	AuditMemberBanRemove     = "member_ban_remove"
+	AuditInviteCreate        = "invite_create"
+	AuditInviteDelete        = "invite_delete"
+	AuditMessageDelete       = "message_delete"
+)
+
+// Tipos de alvo de uma entrada do registro de auditoria.
+const (
+	AuditTargetServer  = "server"
+	AuditTargetChannel = "channel"
+	AuditTargetRole    = "role"
+	AuditTargetUser    = "user"
+	AuditTargetInvite  = "invite"
+	AuditTargetMessage = "message"
+)
+
+// AuditChange é a alteração de um campo. Old é nulo em criações e New em remoções.
+type AuditChange struct {
+	Key string      `json:"key"`
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// auditEntry descreve uma ação a registrar com recordAudit.
+type auditEntry struct {
+	ServerID   uint
+	ActorID    uint
+	Action     string
+	TargetType string
+	TargetID   uint
+	Changes    []AuditChange
+	Options    map[string]interface{} // Dados extras, como o canal de uma mensagem apagada
+	Reason     string
+}
+
+// recordAudit grava uma entrada no registro de auditoria. Deve ser chamado na
+// mesma transação que a ação registrada.
+func recordAudit(tx *gorm.DB, entry auditEntry) error {
+	record := models.AuditLogEntry{
+		ServerID:   entry.ServerID,
+		ActorID:    entry.ActorID,
+		ActionType: entry.Action,
+		TargetType: entry.TargetType,
+		TargetID:   entry.TargetID,
+		Reason:     entry.Reason,
+	}
+	if len(entry.Changes) > 0 {
+		changes, err := json.Marshal(entry.Changes)
+		if err != nil {
+			return err
+		}
+		record.Changes = string(changes)
+	}
+	if len(entry.Options) > 0 {
+		options, err := json.Marshal(entry.Options)
+		if err != nil {
+			return err
+		}
+		record.Options = string(options)
+	}
+	return tx.Create(&record).Error
+}
+
+// auditDiff lista, em ordem de chave, os campos cujo valor difere entre before
+// e after. Um mapa nulo representa a criação (before) ou a remoção (after).
+func auditDiff(before, after map[string]interface{}) []AuditChange {
+	keys := make(map[string]bool, len(before)+len(after))
+	for key := range before {
+		keys[key] = true
+	}
+	for key := range after {
+		keys[key] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	changes := []AuditChange{}
+	for _, key := range sorted {
+		oldValue, newValue := auditValue(before[key]), auditValue(after[key])
+		if reflect.DeepEqual(oldValue, newValue) {
+			continue
+		}
+		changes = append(changes, AuditChange{Key: key, Old: oldValue, New: newValue})
+	}
+	return changes
+}
+
+// auditValue trata ponteiros nulos (como um ParentID vazio) como ausência de valor.
+func auditValue(v interface{}) interface{} {
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Pointer && rv.IsNil() {
+		return nil
+	}
+	return v
+}
+
+// auditReason lê o motivo opcional do cabeçalho X-Audit-Log-Reason.
+func auditReason(c *gin.Context) string {
+	raw := c.GetHeader(auditReasonHeader)
+	reason, err := url.QueryUnescape(raw)
+	if err != nil {
+		reason = raw
+	}
+	return truncateRunes(strings.TrimSpace(reason), maxAuditReasonLength)
+}
+
+// Campos registrados de cada tipo de alvo, usados com auditDiff.
+
+func serverAuditFields(server models.Server) map[string]interface{} {
+	return map[string]interface{}{
+		"name":        server.ServerName,
+		"description": server.Description,
+		"icon_url":    server.IconURL,
+		"owner_id":    server.OwnerID,
+	}
+}
+
+func channelAuditFields(channel models.Channel) map[string]interface{} {
+	return map[string]interface{}{
+		"name":               channel.ChannelName,
+		"type":               channel.ChannelType,
+		"topic":              channel.Topic,
+		"position":           channel.Position,
+		"parent_id":          channel.ParentID,
+		"bitrate":            channel.Bitrate,
+		"user_limit":         channel.UserLimit,
+		"permissions_synced": channel.PermissionsSynced,
+	}
+}
+
+func roleAuditFields(role models.Role) map[string]interface{} {
+	return map[string]interface{}{
+		"name":        role.Name,
+		"color":       role.Color,
+		"permissions": role.Permissions,
+		"position":    role.Position,
+	}
+}
+
+func overwriteAuditFields(overwrite models.PermissionOverwrite) map[string]interface{} {
+	return map[string]interface{}{
+		"allow": overwrite.Allow,
+		"deny":  overwrite.Deny,
+	}
+}
+
+// findOverwriteAuditFields devolve os campos da sobrescrita atual do alvo no
+// canal, ou nil se ela não existe.
+func findOverwriteAuditFields(tx *gorm.DB, channelID uint, targetType string, targetID uint) (map[string]interface{}, error) {
+	var overwrites []models.PermissionOverwrite
+	if err := tx.Where("channel_id = ? AND target_type = ? AND target_id = ?", channelID, targetType, targetID).
+		Limit(1).Find(&overwrites).Error; err != nil {
+		return nil, err
+	}
+	if len(overwrites) == 0 {
+		return nil, nil
+	}
+	return overwriteAuditFields(overwrites[0]), nil
+}
+
+func memberAuditFields(member models.ServerMember) map[string]interface{} {
+	return map[string]interface{}{
+		"nickname":      member.Nickname,
+		"timeout_until": member.TimeoutUntil,
+	}
+}
+
+func inviteAuditFields(invite models.Invite) map[string]interface{} {
+	return map[string]interface{}{
+		"code":       invite.Code,
+		"max_uses":   invite.MaxUses,
+		"uses":       invite.Uses,
+		"expires_at": invite.ExpiresAt,
+	}
+}
+
+type AuditLogHandler struct {
+	DB *gorm.DB
+}
+
+func NewAuditLogHandler(db *gorm.DB) *AuditLogHandler {
+	return &AuditLogHandler{DB: db}
+}
+
+type AuditLogEntryResponse struct {
+	ID         uint            `json:"id"`
+	Actor      UserSummary     `json:"actor"`
+	ActionType string          `json:"actionType"`
+	TargetType string          `json:"targetType,omitempty"`
+	TargetID   uint            `json:"targetId,omitempty"`
+	Changes    json.RawMessage `json:"changes"`
+	Options    json.RawMessage `json:"options,omitempty"`
+	Reason     string          `json:"reason,omitempty"`
+	CreatedAt  time.Time       `json:"createdAt"`
+}
+
+// AuditLogPage é a resposta paginada de GET /servers/:serverId/audit-logs, das
+// entradas mais recentes para as mais antigas. NextBefore só é preenchido
+// quando HasMore é verdadeiro.
+type AuditLogPage struct {
+	Entries    []AuditLogEntryResponse `json:"entries"`
+	HasMore    bool                    `json:"hasMore"`
+	NextBefore *uint                   `json:"nextBefore,omitempty"`
+}
+
+func toAuditLogEntryResponse(entry models.AuditLogEntry) AuditLogEntryResponse {
+	response := AuditLogEntryResponse{
+		ID:         entry.ID,
+		Actor:      toUserSummary(entry.Actor),
+		ActionType: entry.ActionType,
+		TargetType: entry.TargetType,
+		TargetID:   entry.TargetID,
+		Changes:    json.RawMessage("[]"),
+		Reason:     entry.Reason,
+		CreatedAt:  entry.CreatedAt,
+	}
+	if entry.Changes != "" {
+		response.Changes = json.RawMessage(entry.Changes)
+	}
+	if entry.Options != "" {
+		response.Options = json.RawMessage(entry.Options)
+	}
+	return response
+}
+
+// parseTimeQuery lê um parâmetro de query opcional no formato RFC 3339. O
+// instante é convertido para o fuso local, o mesmo das datas gravadas.
+func parseTimeQuery(c *gin.Context, name string) (*time.Time, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return nil, err
+	}
+	t = t.Local()
+	return &t, nil
+}
+
+// ListAuditLogs lida com GET /servers/:serverId/audit-logs?actorId=&action=&since=&until=&before=&limit=.
+// since e until são instantes RFC 3339; before é o ID da entrada usada como
+// cursor. Requer VIEW_AUDIT_LOG.
+func (ah *AuditLogHandler) ListAuditLogs(c *gin.Context) {
+	rawUserID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+	userID := rawUserID.(uint)
+
+	serverID, ok := parseIDParam(c, "serverId")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID do servidor inválido"})
+		return
+	}
+
+	limit := defaultAuditLogLimit
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Paginação inválida: limit deve ser positivo."})
+			return
+		}
+		limit = min(parsed, maxAuditLogLimit)
+	}
+	before, err := parseIDQuery(c, "before")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Paginação inválida: before deve ser o ID de uma entrada."})
+		return
+	}
+	actorID, err := parseIDQuery(c, "actorId")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Filtro inválido: actorId deve ser o ID de um usuário."})
+		return
+	}
+	since, err := parseTimeQuery(c, "since")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Filtro inválido: since deve estar no formato RFC 3339."})
+		return
+	}
+	until, err := parseTimeQuery(c, "until")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Filtro inválido: until deve estar no formato RFC 3339."})
+		return
+	}
+
+	if _, err := authorizeServerPermission(ah.DB, userID, serverID, PermissionViewAuditLog); err != nil {
+		respondAuthzError(c, err)
+		return
+	}
+
+	query := ah.DB.Preload("Actor").Where("server_id = ?", serverID)
+	if before != 0 {
+		query = query.Where("id < ?", before)
+	}
+	if actorID != 0 {
+		query = query.Where("actor_id = ?", actorID)
+	}
+	if action := c.Query("action"); action != "" {
+		query = query.Where("action_type = ?", action)
+	}
+	if since != nil {
+		query = query.Where("created_at >= ?", *since)
+	}
+	if until != nil {
+		query = query.Where("created_at < ?", *until)
+	}
+
+	// Busca uma a mais para saber se há outra página
+	var entries []models.AuditLogEntry
+	if err := query.Order("id DESC").Limit(limit + 1).Find(&entries).Error; err != nil {
+		log.Printf("Erro ao listar o registro de auditoria do servidor %d: %v", serverID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao buscar o registro de auditoria."})
+		return
+	}
+	page := AuditLogPage{Entries: make([]AuditLogEntryResponse, 0, len(entries))}
+	if len(entries) > limit {
+		entries = entries[:limit]
+		page.HasMore = true
+		page.NextBefore = &entries[limit-1].ID
+	}
+	for _, entry := range entries {
+		page.Entries = append(page.Entries, toAuditLogEntryResponse(entry))
+	}
+	c.JSON(http.StatusOK, page)
+}
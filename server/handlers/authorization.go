@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gustavoverneck/discordia/server/models"
+	"gorm.io/gorm"
+)
+
+// Erros de autorização compartilhados pelas rotas REST e pelo WebSocket.
+var (
+	errServerNotFound  = errors.New("servidor não encontrado")
+	errChannelNotFound = errors.New("canal não encontrado")
+	errNotServerMember = errors.New("você não é membro deste servidor")
+)
+
+// isServerMember verifica na tabela de junção server_members se o usuário pertence ao servidor.
+func isServerMember(db *gorm.DB, userID, serverID uint) (bool, error) {
+	var count int64
+	err := db.Table("server_members").
+		Where("server_id = ? AND user_id = ?", serverID, userID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// isChannelRecipient verifica na tabela channel_recipients se o usuário participa do DM.
+func isChannelRecipient(db *gorm.DB, userID, channelID uint) (bool, error) {
+	var count int64
+	err := db.Table("channel_recipients").
+		Where("channel_id = ? AND user_id = ?", channelID, userID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// authorizeServerPermission garante que o usuário é membro do servidor e que
+// suas permissões base incluem todos os bits de required.
+func authorizeServerPermission(db *gorm.DB, userID, serverID uint, required int64) (*permissionContext, error) {
+	pc, err := loadPermissionContext(db, userID, serverID)
+	if err != nil {
+		return nil, err
+	}
+	if !pc.has(required) {
+		return nil, errMissingPermission
+	}
+	return pc, nil
+}
+
+// authorizeChannel carrega o canal e garante que o usuário é membro do servidor
+// dono dele (ou participante, no caso de DMs) e que suas permissões no canal
+// incluem todos os bits de required.
+// É o ponto central de autorização para leitura, inscrição e envio de mensagens.
+// Retorna também as permissões efetivas do usuário no canal.
+func authorizeChannel(db *gorm.DB, userID, channelID uint, required int64) (*models.Channel, int64, error) {
+	var channel models.Channel
+	if err := db.First(&channel, channelID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, 0, errChannelNotFound
+		}
+		return nil, 0, err
+	}
+
+	if channel.ServerID == nil {
+		// DMs não têm cargos: quem participa da conversa tem as permissões de DM
+		participant, err := isChannelRecipient(db, userID, channel.ID)
+		if err != nil {
+			return nil, 0, err
+		}
+		if !participant {
+			return nil, 0, errChannelNotFound
+		}
+		if PermissionDM&required != required {
+			return nil, 0, errMissingPermission
+		}
+		// Em um DM 1:1, um bloqueio em qualquer direção impede novas mensagens
+		if channel.ChannelType == ChannelTypeDM && required&PermissionSendMessages != 0 {
+			blocked, err := hasBlockAmongRecipients(db, channel.ID)
+			if err != nil {
+				return nil, 0, err
+			}
+			if blocked {
+				return nil, 0, errUserBlocked
+			}
+		}
+		return &channel, PermissionDM, nil
+	}
+
+	pc, err := loadPermissionContext(db, userID, *channel.ServerID)
+	if err != nil {
+		if errors.Is(err, errServerNotFound) {
+			return nil, 0, errChannelNotFound
+		}
+		return nil, 0, err
+	}
+	perms, err := pc.channelPermissions(db, &channel)
+	if err != nil {
+		return nil, 0, err
+	}
+	if perms&required != required {
+		return nil, 0, errMissingPermission
+	}
+	return &channel, perms, nil
+}
+
+// authzErrorStatus converte um erro de autorização no status HTTP correspondente.
+func authzErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, errServerNotFound), errors.Is(err, errChannelNotFound),
+		errors.Is(err, errMessageNotFound), errors.Is(err, errParentNotFound), errors.Is(err, errCategoryNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errNotServerMember), errors.Is(err, errMissingPermission), errors.Is(err, errNotMessageAuthor),
+		errors.Is(err, errUserBlocked), errors.Is(err, errMemberTimedOut):
+		return http.StatusForbidden
+	case errors.Is(err, errInvalidContent), errors.Is(err, errTooManyAttachments), errors.Is(err, errAttachmentType),
+		errors.Is(err, errNotTextChannel), errors.Is(err, errNestedCategory):
+		return http.StatusBadRequest
+	case errors.Is(err, errAttachmentTooLarge):
+		return http.StatusRequestEntityTooLarge
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// respondAuthzError escreve a resposta HTTP para um erro retornado pelas funções authorize*.
+func respondAuthzError(c *gin.Context, err error) {
+	status := authzErrorStatus(err)
+	if status == http.StatusInternalServerError {
+		log.Printf("Erro ao verificar autorização: %v", err)
+		c.JSON(status, gin.H{"error": "Erro ao verificar permissões."})
+		return
+	}
+	c.JSON(status, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/gustavoverneck/discordia/server/models"
+	"gorm.io/gorm"
+)
+
+var (
+	errCategoryNotFound = errors.New("categoria não encontrada")
+	errNestedCategory   = errors.New("categorias não podem ficar dentro de outra categoria")
+)
+
+// findCategory busca uma categoria garantindo que ela pertence ao servidor.
+func findCategory(db *gorm.DB, serverID, categoryID uint) (*models.Channel, error) {
+	var category models.Channel
+	err := db.Where("id = ? AND server_id = ? AND channel_type = ?", categoryID, serverID, ChannelTypeCategory).
+		First(&category).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errCategoryNotFound
+		}
+		return nil, err
+	}
+	return &category, nil
+}
+
+// syncWithCategory substitui as sobrescritas do canal por cópias das da
+// categoria e marca o canal como sincronizado.
+func syncWithCategory(tx *gorm.DB, channelID, categoryID uint) error {
+	if err := tx.Unscoped().Where("channel_id = ?", channelID).Delete(&models.PermissionOverwrite{}).Error; err != nil {
+		return err
+	}
+	var overwrites []models.PermissionOverwrite
+	if err := tx.Where("channel_id = ?", categoryID).Find(&overwrites).Error; err != nil {
+		return err
+	}
+	for _, ow := range overwrites {
+		copied := models.PermissionOverwrite{
+			ChannelID:  channelID,
+			TargetType: ow.TargetType,
+			TargetID:   ow.TargetID,
+			Allow:      ow.Allow,
+			Deny:       ow.Deny,
+		}
+		if err := tx.Create(&copied).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Model(&models.Channel{}).Where("id = ?", channelID).Update("permissions_synced", true).Error
+}
+
+// overwriteChannelIDs devolve os canais afetados por uma alteração nas
+// sobrescritas do canal: uma categoria leva junto os canais sincronizados com
+// ela; um canal alterado diretamente deixa de estar sincronizado.
+func overwriteChannelIDs(tx *gorm.DB, channel *models.Channel) ([]uint, error) {
+	if channel.ChannelType != ChannelTypeCategory {
+		if channel.PermissionsSynced {
+			if err := tx.Model(channel).Update("permissions_synced", false).Error; err != nil {
+				return nil, err
+			}
+		}
+		return []uint{channel.ID}, nil
+	}
+	var ids []uint
+	if err := tx.Model(&models.Channel{}).
+		Where("parent_id = ? AND permissions_synced = ?", channel.ID, true).
+		Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return append(ids, channel.ID), nil
+}
+
+// sortChannelTree ordena os canais como aparecem na lista do servidor: os de
+// primeiro nível (categorias e canais sem categoria) por posição, cada
+// categoria seguida dos seus canais. Canais cuja categoria não está na lista
+// ficam no primeiro nível.
+func sortChannelTree(channels []models.Channel) []models.Channel {
+	present := make(map[uint]bool, len(channels))
+	for _, channel := range channels {
+		present[channel.ID] = true
+	}
+	var top []models.Channel
+	children := make(map[uint][]models.Channel)
+	for _, channel := range channels {
+		if channel.ParentID != nil && present[*channel.ParentID] {
+			children[*channel.ParentID] = append(children[*channel.ParentID], channel)
+		} else {
+			top = append(top, channel)
+		}
+	}
+
+	sortByPosition(top)
+	sorted := make([]models.Channel, 0, len(channels))
+	for _, channel := range top {
+		sorted = append(sorted, channel)
+		nested := children[channel.ID]
+		sortByPosition(nested)
+		sorted = append(sorted, nested...)
+	}
+	return sorted
+}
+
+func sortByPosition(channels []models.Channel) {
+	sort.SliceStable(channels, func(i, j int) bool {
+		if channels[i].Position != channels[j].Position {
+			return channels[i].Position < channels[j].Position
+		}
+		return channels[i].ID < channels[j].ID
+	})
+}
+
+// sameParent informa se dois canais estão na mesma categoria (ou ambos fora de categorias).
+func sameParent(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -1,29 +1,34 @@
 package handlers
 
 import (
-	"errors"
 	"log"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gustavoverneck/discordia/server/models"
+	"github.com/gustavoverneck/discordia/server/storage"
 	"gorm.io/gorm"
 )
 
 type ChannelHandler struct {
-	DB *gorm.DB
+	DB      *gorm.DB
+	Hub     *Hub            // Usado para notificar as sessões inscritas sobre alterações feitas via REST
+	Storage storage.Storage // Onde os anexos das mensagens são gravados
 }
 
-func NewChannelHandler(db *gorm.DB) *ChannelHandler {
-	return &ChannelHandler{DB: db}
+func NewChannelHandler(db *gorm.DB, hub *Hub, store storage.Storage) *ChannelHandler {
+	return &ChannelHandler{DB: db, Hub: hub, Storage: store}
 }
 
 type CreateChannelRequest struct {
 	ChannelName string `json:"channelName" binding:"required,min=1,max=100"`
-	ChannelType string `json:"channelType" binding:"required,oneof=TEXT VOICE"` // Valida o tipo
-	// Topic       string `json:"topic,omitempty"` // Opcional para canais de texto
+	ChannelType string `json:"channelType" binding:"required,oneof=TEXT VOICE CATEGORY"` // Valida o tipo
+	Topic       string `json:"topic,omitempty" binding:"max=1024"`                       // Opcional para canais de texto
+	ParentID    *uint  `json:"parentId,omitempty"`                                       // Categoria; o canal herda as permissões dela
 }
 
 func (ch *ChannelHandler) CreateChannel(c *gin.Context) {
@@ -48,47 +53,84 @@ func (ch *ChannelHandler) CreateChannel(c *gin.Context) {
 		return
 	}
 
-	// Verificar se o usuário tem permissão para criar canal (ex: é o proprietário do servidor)
-	var server models.Server
-	if errDb := ch.DB.First(&server, serverIDUint).Error; errDb != nil {
-		if errors.Is(errDb, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Servidor não encontrado"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao verificar servidor"})
+	// Verificar se o usuário é membro e tem permissão para gerenciar canais
+	if _, err := authorizeServerPermission(ch.DB, userID, serverIDUint, PermissionManageChannels); err != nil {
+		respondAuthzError(c, err)
 		return
 	}
 
-	if server.OwnerID != userID {
-		// Por enquanto, apenas o dono pode criar. Você pode expandir essa lógica de permissão.
-		c.JSON(http.StatusForbidden, gin.H{"error": "Apenas o proprietário do servidor pode criar canais."})
+	if req.Topic != "" && req.ChannelType != ChannelTypeText {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "O tópico só pode ser definido em canais de texto."})
 		return
 	}
 
+	if req.ParentID != nil {
+		if req.ChannelType == ChannelTypeCategory {
+			respondAuthzError(c, errNestedCategory)
+			return
+		}
+		if _, err := findCategory(ch.DB, serverIDUint, *req.ParentID); err != nil {
+			respondAuthzError(c, err)
+			return
+		}
+	}
+
 	// Criar o canal
 	newChannel := models.Channel{
-		ServerID:    serverIDUint,
+		ServerID:    &serverIDUint,
 		ChannelName: req.ChannelName,
 		ChannelType: req.ChannelType,
-		// Topic:       req.Topic, // Se adicionar topic
+		Topic:       req.Topic,
+		ParentID:    req.ParentID,
+	}
+	if req.ChannelType == ChannelTypeVoice {
+		newChannel.Bitrate = defaultVoiceBitrate
 	}
 
-	if result := ch.DB.Create(&newChannel); result.Error != nil {
-		log.Printf("Erro ao criar canal no servidor %d: %v", serverIDUint, result.Error)
+	err = ch.DB.Transaction(func(tx *gorm.DB) error {
+		// O novo canal vai para o fim da sua categoria (ou do primeiro nível)
+		position, err := nextChannelPosition(tx, serverIDUint, req.ParentID)
+		if err != nil {
+			return err
+		}
+		newChannel.Position = position
+		if err := tx.Create(&newChannel).Error; err != nil {
+			return err
+		}
+		if req.ParentID != nil {
+			newChannel.PermissionsSynced = true
+			if err := syncWithCategory(tx, newChannel.ID, *req.ParentID); err != nil {
+				return err
+			}
+		}
+		return recordAudit(tx, auditEntry{
+			ServerID:   serverIDUint,
+			ActorID:    userID,
+			Action:     AuditChannelCreate,
+			TargetType: AuditTargetChannel,
+			TargetID:   newChannel.ID,
+			Changes:    auditDiff(nil, channelAuditFields(newChannel)),
+			Reason:     auditReason(c),
+		})
+	})
+	if err != nil {
+		log.Printf("Erro ao criar canal no servidor %d: %v", serverIDUint, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao criar o canal."})
 		return
 	}
 
+	viewers := ch.channelAudience(serverIDUint, []models.Channel{newChannel})
+	ch.Hub.DispatchToUsers(viewers[newChannel.ID], "channel_create", newChannel)
 	c.JSON(http.StatusCreated, newChannel)
 }
 
 func (ch *ChannelHandler) ListChannels(c *gin.Context) {
-	_, exists := c.Get("userID") // Verifica se o usuário está autenticado para ver canais
+	rawUserID, exists := c.Get("userID") // Verifica se o usuário está autenticado para ver canais
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
 		return
 	}
-	// Poderia adicionar uma verificação se o usuário é membro do servidor para listar canais
+	userID := rawUserID.(uint)
 
 	serverIDStr := c.Param("serverId")
 	serverID, err := strconv.ParseUint(serverIDStr, 10, 32)
@@ -98,40 +140,550 @@ func (ch *ChannelHandler) ListChannels(c *gin.Context) {
 	}
 	serverIDUint := uint(serverID)
 
-	var channels []models.Channel
-	// Adicionar .Order("position ASC").Order("created_at ASC") para ordenação, se tiver esses campos
-	if errDb := ch.DB.Where("server_id = ?", serverIDUint).Order("created_at ASC").Find(&channels).Error; errDb != nil {
-		log.Printf("Erro ao listar canais do servidor %d: %v", serverIDUint, errDb)
+	// Apenas membros do servidor podem listar seus canais
+	pc, err := loadPermissionContext(ch.DB, userID, serverIDUint)
+	if err != nil {
+		respondAuthzError(c, err)
+		return
+	}
+
+	// Carrega as permissões de todos os canais de uma vez (em ordem de posição) e mantém apenas os visíveis ao usuário
+	channels, perms, err := pc.serverChannelPermissions(ch.DB)
+	if err != nil {
+		log.Printf("Erro ao listar canais do servidor %d: %v", serverIDUint, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao buscar canais."})
 		return
 	}
 
-	if channels == nil { // Garante que um array vazio seja retornado em vez de null
-		channels = []models.Channel{}
+	// Uma categoria aparece se o usuário a vê ou vê algum canal dentro dela
+	visible := make(map[uint]bool, len(channels))
+	for _, channel := range channels {
+		if perms[channel.ID]&PermissionViewChannel != 0 {
+			visible[channel.ID] = true
+			if channel.ParentID != nil {
+				visible[*channel.ParentID] = true
+			}
+		}
+	}
+	var visibleList []models.Channel
+	var visibleIDs []uint
+	for _, channel := range channels {
+		if visible[channel.ID] {
+			visibleList = append(visibleList, channel)
+			visibleIDs = append(visibleIDs, channel.ID)
+		}
+	}
+	readStates, err := loadReadStates(ch.DB, userID, visibleIDs)
+	if err != nil {
+		log.Printf("Erro ao carregar estado de leitura dos canais do servidor %d: %v", serverIDUint, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao buscar canais."})
+		return
+	}
+
+	// A lista segue a hierarquia: cada categoria seguida dos seus canais (ParentID)
+	visibleChannels := []ChannelWithReadState{} // Garante que um array vazio seja retornado em vez de null
+	for _, channel := range sortChannelTree(visibleList) {
+		visibleChannels = append(visibleChannels, ChannelWithReadState{Channel: channel, ChannelReadState: readStates[channel.ID]})
+	}
+	c.JSON(http.StatusOK, visibleChannels)
+}
+
+// defaultVoiceBitrate é o bitrate de um canal de voz novo, em bits/s.
+const defaultVoiceBitrate = 64000
+
+type UpdateChannelRequest struct {
+	ChannelName     *string `json:"channelName" binding:"omitempty,min=1,max=100"`
+	Topic           *string `json:"topic" binding:"omitempty,max=1024"`              // Apenas canais de texto
+	Bitrate         *int    `json:"bitrate" binding:"omitempty,min=8000,max=384000"` // Apenas canais de voz
+	UserLimit       *int    `json:"userLimit" binding:"omitempty,min=0,max=99"`      // Apenas canais de voz
+	SyncPermissions bool    `json:"syncPermissions"`                                 // Copia de novo as permissões da categoria
+}
+
+// ChannelPositionRequest é um item do corpo de PATCH /servers/:serverId/channels.
+type ChannelPositionRequest struct {
+	ID              uint  `json:"id" binding:"required"`
+	Position        int   `json:"position" binding:"min=0"` // Posição entre os canais da mesma categoria
+	ParentID        *uint `json:"parentId"`                 // Move o canal para a categoria; 0 = fora de categorias
+	LockPermissions bool  `json:"lockPermissions"`          // Ao mudar de categoria, sincroniza as permissões com a nova
+}
+
+type ChannelDeletePayload struct {
+	ID       uint `json:"id"`
+	ServerID uint `json:"serverId"`
+}
+
+// channelAudience devolve quem pode ver cada canal, para enviar os eventos de
+// canal apenas a esses membros. Em caso de erro nenhum evento é enviado.
+func (ch *ChannelHandler) channelAudience(serverID uint, channels []models.Channel) map[uint][]uint {
+	viewers, err := channelViewers(ch.DB, serverID, channels)
+	if err != nil {
+		log.Printf("Erro ao calcular destinatários dos eventos de canal do servidor %d: %v", serverID, err)
+		return nil
+	}
+	return viewers
+}
+
+// dispatchChannelUpdates envia channel_update de cada canal a quem pode vê-lo.
+func (ch *ChannelHandler) dispatchChannelUpdates(serverID uint, channels []models.Channel) {
+	if len(channels) == 0 {
+		return
+	}
+	viewers := ch.channelAudience(serverID, channels)
+	for _, channel := range channels {
+		ch.Hub.DispatchToUsers(viewers[channel.ID], "channel_update", channel)
+	}
+}
+
+// nextChannelPosition devolve a posição logo após o último canal da categoria
+// (ou do primeiro nível, se parentID for nulo).
+func nextChannelPosition(tx *gorm.DB, serverID uint, parentID *uint) (int, error) {
+	siblings := tx.Model(&models.Channel{}).Select("MAX(position) AS position").Where("server_id = ?", serverID)
+	if parentID != nil {
+		siblings = siblings.Where("parent_id = ?", *parentID)
+	} else {
+		siblings = siblings.Where("parent_id IS NULL")
+	}
+	var last struct{ Position *int }
+	if err := siblings.Scan(&last).Error; err != nil {
+		return 0, err
+	}
+	if last.Position == nil {
+		return 0, nil
+	}
+	return *last.Position + 1, nil
+}
+
+// authorizeServerChannel carrega um canal de servidor exigindo MANAGE_CHANNELS.
+// Em caso de erro a resposta HTTP já foi escrita.
+func (ch *ChannelHandler) authorizeServerChannel(c *gin.Context, userID, channelID uint) (*models.Channel, int64, bool) {
+	channel, perms, err := authorizeChannel(ch.DB, userID, channelID, PermissionViewChannel|PermissionManageChannels)
+	if err != nil {
+		respondAuthzError(c, err)
+		return nil, 0, false
+	}
+	if channel.ServerID == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Apenas canais de servidor podem ser alterados por esta rota."})
+		return nil, 0, false
+	}
+	return channel, perms, true
+}
+
+// UpdateChannel lida com PATCH /channels/:channelId: nome, tópico (texto),
+// bitrate e limite de usuários (voz) e a sincronização das permissões com a
+// categoria. Requer MANAGE_CHANNELS; sincronizar requer também MANAGE_ROLES.
+func (ch *ChannelHandler) UpdateChannel(c *gin.Context) {
+	rawUserID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+	userID := rawUserID.(uint)
+
+	channelID, ok := parseIDParam(c, "channelId")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID do canal inválido"})
+		return
+	}
+
+	var req UpdateChannelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos: " + err.Error()})
+		return
+	}
+
+	channel, perms, ok := ch.authorizeServerChannel(c, userID, channelID)
+	if !ok {
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.ChannelName != nil {
+		name := strings.TrimSpace(*req.ChannelName)
+		if name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Nome do canal é obrigatório"})
+			return
+		}
+		updates["channel_name"] = name
+	}
+	if req.Topic != nil {
+		if channel.ChannelType != ChannelTypeText {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "O tópico só pode ser definido em canais de texto."})
+			return
+		}
+		updates["topic"] = strings.TrimSpace(*req.Topic)
+	}
+	if req.Bitrate != nil || req.UserLimit != nil {
+		if channel.ChannelType != ChannelTypeVoice {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Bitrate e limite de usuários só existem em canais de voz."})
+			return
+		}
+		if req.Bitrate != nil {
+			updates["bitrate"] = *req.Bitrate
+		}
+		if req.UserLimit != nil {
+			updates["user_limit"] = *req.UserLimit
+		}
+	}
+	if req.SyncPermissions {
+		if channel.ParentID == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "O canal não está em uma categoria."})
+			return
+		}
+		if perms&PermissionManageRoles == 0 {
+			respondAuthzError(c, errMissingPermission)
+			return
+		}
+	}
+	if len(updates) == 0 && !req.SyncPermissions {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Nenhuma alteração informada."})
+		return
+	}
+
+	before := channelAuditFields(*channel)
+	err := ch.DB.Transaction(func(tx *gorm.DB) error {
+		if len(updates) > 0 {
+			if err := tx.Model(channel).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		if req.SyncPermissions {
+			channel.PermissionsSynced = true
+			if err := syncWithCategory(tx, channel.ID, *channel.ParentID); err != nil {
+				return err
+			}
+		}
+		return recordAudit(tx, auditEntry{
+			ServerID:   *channel.ServerID,
+			ActorID:    userID,
+			Action:     AuditChannelUpdate,
+			TargetType: AuditTargetChannel,
+			TargetID:   channel.ID,
+			Changes:    auditDiff(before, channelAuditFields(*channel)),
+			Reason:     auditReason(c),
+		})
+	})
+	if err != nil {
+		log.Printf("Erro ao atualizar canal %d: %v", channelID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao atualizar o canal."})
+		return
+	}
+
+	ch.dispatchChannelUpdates(*channel.ServerID, []models.Channel{*channel})
+	c.JSON(http.StatusOK, channel)
+}
+
+// DeleteChannel lida com DELETE /channels/:channelId. As mensagens do canal são
+// apagadas junto dele; os canais de uma categoria apagada vão para o fim do
+// primeiro nível. Requer MANAGE_CHANNELS.
+func (ch *ChannelHandler) DeleteChannel(c *gin.Context) {
+	rawUserID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+	userID := rawUserID.(uint)
+
+	channelID, ok := parseIDParam(c, "channelId")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID do canal inválido"})
+		return
+	}
+
+	channel, _, ok := ch.authorizeServerChannel(c, userID, channelID)
+	if !ok {
+		return
+	}
+	serverID := *channel.ServerID
+	// Quem via o canal precisa ser calculado antes de as sobrescritas serem apagadas
+	viewers := ch.channelAudience(serverID, []models.Channel{*channel})
+
+	var children []models.Channel
+	var attachments []models.Attachment
+	err := ch.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("parent_id = ?", channel.ID).Order("position ASC, id ASC").Find(&children).Error; err != nil {
+			return err
+		}
+		if len(children) > 0 {
+			position, err := nextChannelPosition(tx, serverID, nil)
+			if err != nil {
+				return err
+			}
+			for i := range children {
+				children[i].ParentID = nil
+				children[i].PermissionsSynced = false
+				children[i].Position = position + i
+				if err := tx.Model(&children[i]).Updates(map[string]interface{}{
+					"parent_id":          nil,
+					"permissions_synced": false,
+					"position":           children[i].Position,
+				}).Error; err != nil {
+					return err
+				}
+			}
+		}
+		deleted, err := deleteChannelContents(tx, []uint{channel.ID})
+		if err != nil {
+			return err
+		}
+		attachments = deleted
+		if err := tx.Delete(channel).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, auditEntry{
+			ServerID:   serverID,
+			ActorID:    userID,
+			Action:     AuditChannelDelete,
+			TargetType: AuditTargetChannel,
+			TargetID:   channel.ID,
+			Changes:    auditDiff(channelAuditFields(*channel), nil),
+			Reason:     auditReason(c),
+		})
+	})
+	if err != nil {
+		log.Printf("Erro ao apagar canal %d: %v", channelID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao apagar o canal."})
+		return
+	}
+	removeAttachmentFiles(c.Request.Context(), ch.Storage, attachments)
+
+	for _, viewerID := range viewers[channel.ID] {
+		ch.Hub.UnsubscribeUser(viewerID, channel.ID)
+	}
+	ch.Hub.DispatchToUsers(viewers[channel.ID], "channel_delete", ChannelDeletePayload{ID: channel.ID, ServerID: serverID})
+	ch.dispatchChannelUpdates(serverID, children)
+	c.Status(http.StatusNoContent)
+}
+
+// ReorderChannels lida com PATCH /servers/:serverId/channels, usado ao arrastar
+// canais na lista, inclusive para dentro ou fora de categorias. Os canais
+// informados vão para as posições pedidas e os canais de cada categoria (e do
+// primeiro nível) são renumerados (0, 1, 2...) na mesma transação; em caso de
+// empate, os canais informados ficam à frente. Requer MANAGE_CHANNELS.
+func (ch *ChannelHandler) ReorderChannels(c *gin.Context) {
+	rawUserID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+	userID := rawUserID.(uint)
+
+	serverID, ok := parseIDParam(c, "serverId")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID do servidor inválido"})
+		return
+	}
+
+	var req []ChannelPositionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos: " + err.Error()})
+		return
+	}
+	if len(req) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Nenhuma posição informada."})
+		return
+	}
+	requested := make(map[uint]ChannelPositionRequest, len(req))
+	for _, item := range req {
+		if _, dup := requested[item.ID]; dup {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Canal repetido na lista de posições."})
+			return
+		}
+		requested[item.ID] = item
+	}
+
+	if _, err := authorizeServerPermission(ch.DB, userID, serverID, PermissionManageChannels); err != nil {
+		respondAuthzError(c, err)
+		return
 	}
-	c.JSON(http.StatusOK, channels)
+
+	var changed []models.Channel
+	err := ch.DB.Transaction(func(tx *gorm.DB) error {
+		var channels []models.Channel
+		if err := tx.Where("server_id = ?", serverID).Order("position ASC, id ASC").Find(&channels).Error; err != nil {
+			return err
+		}
+		stored := make(map[uint]models.Channel, len(channels))
+		for _, channel := range channels {
+			stored[channel.ID] = channel
+		}
+
+		found := 0
+		for i := range channels {
+			item, ok := requested[channels[i].ID]
+			if !ok {
+				continue
+			}
+			found++
+			channels[i].Position = item.Position
+			if item.ParentID == nil {
+				continue
+			}
+			if *item.ParentID == 0 {
+				channels[i].ParentID = nil
+				continue
+			}
+			if channels[i].ChannelType == ChannelTypeCategory {
+				return errNestedCategory
+			}
+			if parent, ok := stored[*item.ParentID]; !ok || parent.ChannelType != ChannelTypeCategory {
+				return errCategoryNotFound
+			}
+			parentID := *item.ParentID
+			channels[i].ParentID = &parentID
+		}
+		if found != len(requested) {
+			return errChannelNotFound
+		}
+
+		// Renumera cada grupo de canais irmãos (mesma categoria) separadamente
+		groups := make(map[uint][]*models.Channel)
+		for i := range channels {
+			var key uint
+			if channels[i].ParentID != nil {
+				key = *channels[i].ParentID
+			}
+			groups[key] = append(groups[key], &channels[i])
+		}
+		for _, group := range groups {
+			sort.SliceStable(group, func(i, j int) bool {
+				if group[i].Position != group[j].Position {
+					return group[i].Position < group[j].Position
+				}
+				_, iRequested := requested[group[i].ID]
+				_, jRequested := requested[group[j].ID]
+				return iRequested && !jRequested
+			})
+			for i, channel := range group {
+				channel.Position = i
+			}
+		}
+
+		for i := range channels {
+			channel := &channels[i]
+			before := stored[channel.ID]
+			moved := !sameParent(before.ParentID, channel.ParentID)
+			if !moved && before.Position == channel.Position {
+				continue
+			}
+			updates := map[string]interface{}{"position": channel.Position}
+			if moved {
+				updates["parent_id"] = channel.ParentID
+				channel.PermissionsSynced = false
+				updates["permissions_synced"] = false
+			}
+			if err := tx.Model(channel).Updates(updates).Error; err != nil {
+				return err
+			}
+			if moved && channel.ParentID != nil && requested[channel.ID].LockPermissions {
+				channel.PermissionsSynced = true
+				if err := syncWithCategory(tx, channel.ID, *channel.ParentID); err != nil {
+					return err
+				}
+			}
+			changed = append(changed, *channel)
+
+			// Só os canais movidos pelo usuário são registrados, não os irmãos renumerados
+			if _, ok := requested[channel.ID]; !ok {
+				continue
+			}
+			if err := recordAudit(tx, auditEntry{
+				ServerID:   serverID,
+				ActorID:    userID,
+				Action:     AuditChannelUpdate,
+				TargetType: AuditTargetChannel,
+				TargetID:   channel.ID,
+				Changes:    auditDiff(channelAuditFields(before), channelAuditFields(*channel)),
+				Reason:     auditReason(c),
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if status := authzErrorStatus(err); status != http.StatusInternalServerError {
+			respondAuthzError(c, err)
+			return
+		}
+		log.Printf("Erro ao reordenar canais do servidor %d: %v", serverID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao reordenar os canais."})
+		return
+	}
+
+	ch.dispatchChannelUpdates(serverID, changed)
+	c.Status(http.StatusNoContent)
+}
+
+// UserSummary é o resumo público de um usuário (autor de mensagem, participante, etc.).
+type UserSummary struct {
+	ID          uint   `json:"id"`
+	Username    string `json:"username"`
+	DisplayName string `json:"displayName,omitempty"`
+	AvatarURL   string `json:"avatarUrl,omitempty"`
 }
 
 type MessageResponse struct {
-	ID        uint      `json:"id"`
-	Content   string    `json:"content"`
-	CreatedAt time.Time `json:"createdAt"`
-	Author    struct {  // Incluir informações do autor
-		ID        uint   `json:"id"`
-		Username  string `json:"username"`
-		AvatarURL string `json:"avatarUrl,omitempty"`
-	} `json:"author"`
-	ChannelID uint `json:"channelId"`
+	ID              uint                 `json:"id"`
+	Content         string               `json:"content"`
+	CreatedAt       time.Time            `json:"createdAt"`
+	EditedAt        *time.Time           `json:"editedAt,omitempty"`
+	Author          UserSummary          `json:"author"` // Incluir informações do autor
+	ChannelID       uint                 `json:"channelId"`
+	ParentMessageID *uint                `json:"parentMessageId,omitempty"`
+	ParentMessage   *MessagePreview      `json:"parentMessage,omitempty"` // Ausente se a mensagem original foi apagada
+	ReplyCount      int64                `json:"replyCount"`
+	Attachments     []AttachmentResponse `json:"attachments"`
+}
+
+// MessagePreview é a citação resumida da mensagem respondida.
+type MessagePreview struct {
+	ID      uint        `json:"id"`
+	Content string      `json:"content"`
+	Author  UserSummary `json:"author"`
+}
+
+func toUserSummary(user models.User) UserSummary {
+	return UserSummary{
+		ID:          user.ID,
+		Username:    user.Username,
+		DisplayName: user.DisplayName,
+		AvatarURL:   user.AvatarURL,
+	}
+}
+
+// toMessageResponse converte uma mensagem (com o autor carregado) no DTO enviado aos clientes.
+// A contagem de respostas é preenchida por buildMessageResponses.
+func toMessageResponse(msg models.Message) MessageResponse {
+	response := MessageResponse{
+		ID:              msg.ID,
+		Content:         msg.Content,
+		CreatedAt:       msg.CreatedAt,
+		EditedAt:        msg.EditedAt,
+		Author:          toUserSummary(msg.Author),
+		ChannelID:       msg.ChannelID,
+		ParentMessageID: msg.ParentMessageID,
+		Attachments:     make([]AttachmentResponse, 0, len(msg.Attachments)),
+	}
+	for _, attachment := range msg.Attachments {
+		response.Attachments = append(response.Attachments, toAttachmentResponse(attachment))
+	}
+	if msg.ParentMessage != nil {
+		response.ParentMessage = &MessagePreview{
+			ID:      msg.ParentMessage.ID,
+			Content: truncateRunes(msg.ParentMessage.Content, messagePreviewLength),
+			Author:  toUserSummary(msg.ParentMessage.Author),
+		}
+	}
+	return response
 }
 
 func (ch *ChannelHandler) ListMessagesInChannel(c *gin.Context) {
-	_, userAuthenticated := c.Get("userID")
+	rawUserID, userAuthenticated := c.Get("userID")
 	if !userAuthenticated {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
 		return
 	}
-	// Aqui você também verificaria se o usuário tem permissão para ver este canal
-	// (ex: é membro do servidor ao qual o canal pertence). Por simplicidade, omitido por agora.
+	userID := rawUserID.(uint)
 
 	channelIDStr := c.Param("channelId")
 	channelID, err := strconv.ParseUint(channelIDStr, 10, 32)
@@ -141,79 +693,40 @@ func (ch *ChannelHandler) ListMessagesInChannel(c *gin.Context) {
 	}
 	channelIDUint := uint(channelID)
 
-	// Validação se o canal é de TEXTO (opcional, mas bom)
-	var channel models.Channel
-	if errDb := ch.DB.First(&channel, channelIDUint).Error; errDb != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Canal não encontrado"})
+	// O usuário precisa poder ver o canal e ler seu histórico
+	channel, _, err := authorizeChannel(ch.DB, userID, channelIDUint, PermissionViewChannel|PermissionReadMessageHistory)
+	if err != nil {
+		respondAuthzError(c, err)
 		return
 	}
-	if channel.ChannelType != "TEXT" {
+	// Canais de voz e categorias não têm histórico de texto; DMs e DMs em grupo sim
+	if !supportsText(channel.ChannelType) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Este canal não suporta mensagens de texto."})
 		return
 	}
 
-	// Paginação (simples, baseada em limit e offset/before)
-	limitStr := c.DefaultQuery("limit", "50") // Padrão de 50 mensagens
-	limit, _ := strconv.Atoi(limitStr)
-	if limit > 100 { // Limite máximo
-		limit = 100
+	// Paginação por cursor: ?limit=&before=|after=|around=<id da mensagem>
+	cursor, err := parseMessageCursor(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Paginação inválida: limit deve ser positivo e apenas um entre before, after e around pode ser usado."})
+		return
 	}
 
-	// Para carregar mensagens mais antigas (antes de um certo ID ou timestamp)
-	// beforeIDStr := c.Query("before") // ID da mensagem antes da qual buscar
-	// var beforeID uint64
-	// if beforeIDStr != "" {
-	// 	beforeID, _ = strconv.ParseUint(beforeIDStr, 10, 32)
-	// }
-
-	var messages []models.Message
-	query := ch.DB.Where("channel_id = ?", channelIDUint).
-		Order("created_at DESC"). // Mais recentes primeiro (ou ASC se preferir)
-		Limit(limit).
-		Preload("Author") // IMPORTANTE: Carregar dados do autor
-
-	// if beforeID > 0 {
-	// 	query = query.Where("id < ?", uint(beforeID)) // Paginação por cursor
-	// }
-
-	if errDb := query.Find(&messages).Error; errDb != nil {
-		log.Printf("Erro ao buscar mensagens para o canal %d: %v", channelIDUint, errDb)
+	messages, hasMoreBefore, hasMoreAfter, err := fetchMessagePage(ch.DB.Where("channel_id = ?", channelIDUint), cursor)
+	if err != nil {
+		log.Printf("Erro ao buscar mensagens para o canal %d: %v", channelIDUint, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao buscar mensagens."})
 		return
 	}
 
-	// Mapear para DTOs de resposta
-	var messageResponses []MessageResponse
-	for _, msg := range messages {
-		// É importante inverter a ordem aqui se você buscou DESC para paginação
-		// mas quer exibir ASC no frontend (ou o frontend inverte).
-		// Por agora, vamos manter a ordem de busca.
-		// O frontend geralmente exibe as mais recentes no final (embaixo).
-		// Se buscou DESC, a lista 'messages' está das mais novas para as mais antigas.
-		// O frontend pode querer inverter isso para renderizar.
-		authorData := struct {
-			ID        uint   `json:"id"`
-			Username  string `json:"username"`
-			AvatarURL string `json:"avatarUrl,omitempty"`
-		}{
-			ID:        msg.Author.ID, // Assume que Author.ID é populado (vem de gorm.Model)
-			Username:  msg.Author.Username,
-			AvatarURL: msg.Author.AvatarURL,
-		}
-		messageResponses = append(messageResponses, MessageResponse{
-			ID:        msg.ID,
-			Content:   msg.Content,
-			CreatedAt: msg.CreatedAt,
-			Author:    authorData,
-			ChannelID: msg.ChannelID,
-		})
+	// Mapear para DTOs de resposta, mantendo a ordem decrescente (mais recentes primeiro).
+	// O frontend inverte a lista para renderizar.
+	messageResponses, err := buildMessageResponses(ch.DB, messages)
+	if err != nil {
+		log.Printf("Erro ao contar respostas das mensagens do canal %d: %v", channelIDUint, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao buscar mensagens."})
+		return
 	}
-	// Se você buscou em ordem DESC, mas o frontend espera ASC (mais antigas primeiro)
-	// você pode inverter o slice messageResponses aqui antes de enviar.
-	// Exemplo de inversão (se necessário):
-	// for i, j := 0, len(messageResponses)-1; i < j; i, j = i+1, j-1 {
-	//    messageResponses[i], messageResponses[j] = messageResponses[j], messageResponses[i]
-	// }
 
-	c.JSON(http.StatusOK, messageResponses)
+	c.JSON(http.StatusOK, newMessagePage(messageResponses, hasMoreBefore, hasMoreAfter))
 }
@@ -0,0 +1,425 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gustavoverneck/discordia/server/models"
+	"gorm.io/gorm"
+)
+
+// Tipos de canal (models.Channel.ChannelType).
+const (
+	ChannelTypeText     = "TEXT"
+	ChannelTypeVoice    = "VOICE"
+	ChannelTypeCategory = "CATEGORY"
+	ChannelTypeDM       = "DM"
+	ChannelTypeGroupDM  = "GROUP_DM"
+)
+
+// maxGroupDMRecipients é o número máximo de participantes de um DM em grupo, incluindo o criador.
+const maxGroupDMRecipients = 10
+
+var errGroupDMFull = errors.New("o grupo atingiu o limite de participantes")
+
+type DMHandler struct {
+	DB  *gorm.DB
+	Hub *Hub
+}
+
+func NewDMHandler(db *gorm.DB, hub *Hub) *DMHandler {
+	return &DMHandler{DB: db, Hub: hub}
+}
+
+type CreateDMRequest struct {
+	// Um destinatário abre (ou reabre) um DM; dois ou mais criam um DM em grupo.
+	RecipientIDs []uint `json:"recipientIds" binding:"required,min=1,max=9,dive,min=1"`
+	Name         string `json:"name" binding:"omitempty,max=100"` // Apenas para DMs em grupo
+}
+
+type DMChannelResponse struct {
+	ID         uint          `json:"id"`
+	Type       string        `json:"type"`
+	Name       string        `json:"name,omitempty"`
+	OwnerID    *uint         `json:"ownerId,omitempty"`
+	Recipients []UserSummary `json:"recipients"` // Todos os participantes, incluindo o usuário atual
+	ChannelReadState
+}
+
+// DMRecipientPayload é o corpo dos eventos channel_recipient_add e channel_recipient_remove.
+type DMRecipientPayload struct {
+	ChannelID uint        `json:"channelId"`
+	User      UserSummary `json:"user"`
+}
+
+func toDMChannelResponse(channel models.Channel) DMChannelResponse {
+	response := DMChannelResponse{
+		ID:         channel.ID,
+		Type:       channel.ChannelType,
+		Name:       channel.ChannelName,
+		OwnerID:    channel.OwnerID,
+		Recipients: []UserSummary{},
+	}
+	for _, recipient := range channel.Recipients {
+		response.Recipients = append(response.Recipients, toUserSummary(*recipient))
+	}
+	return response
+}
+
+// recipientIDs retorna os IDs dos participantes carregados do canal.
+func recipientIDs(channel *models.Channel) []uint {
+	ids := make([]uint, 0, len(channel.Recipients))
+	for _, recipient := range channel.Recipients {
+		ids = append(ids, recipient.ID)
+	}
+	return ids
+}
+
+// findDMChannel carrega um DM ou DM em grupo com seus participantes.
+func findDMChannel(db *gorm.DB, channelID uint) (*models.Channel, error) {
+	var channel models.Channel
+	err := db.Preload("Recipients").
+		Where("id = ? AND channel_type IN ?", channelID, []string{ChannelTypeDM, ChannelTypeGroupDM}).
+		First(&channel).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errChannelNotFound
+		}
+		return nil, err
+	}
+	return &channel, nil
+}
+
+// findDirectMessage busca o DM (1:1) existente entre dois usuários.
+func findDirectMessage(db *gorm.DB, userID, otherID uint) (*models.Channel, error) {
+	var channel models.Channel
+	err := db.Preload("Recipients").
+		Where("channel_type = ?", ChannelTypeDM).
+		Where("id IN (?)", db.Table("channel_recipients").Select("channel_id").Where("user_id = ?", userID)).
+		Where("id IN (?)", db.Table("channel_recipients").Select("channel_id").Where("user_id = ?", otherID)).
+		First(&channel).Error
+	if err != nil {
+		return nil, err
+	}
+	return &channel, nil
+}
+
+// ListDMChannels lista os DMs e DMs em grupo do usuário, dos mais ativos para os menos ativos.
+func (dh *DMHandler) ListDMChannels(c *gin.Context) {
+	rawUserID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+	userID := rawUserID.(uint)
+
+	var channels []models.Channel
+	err := dh.DB.Model(&models.Channel{}).
+		Select("channels.*, (SELECT MAX(messages.id) FROM messages WHERE messages.channel_id = channels.id AND messages.deleted_at IS NULL) AS last_message_id").
+		Joins("JOIN channel_recipients ON channel_recipients.channel_id = channels.id").
+		Where("channel_recipients.user_id = ? AND channels.channel_type IN ?", userID, []string{ChannelTypeDM, ChannelTypeGroupDM}).
+		Order("COALESCE(last_message_id, 0) DESC, channels.id DESC").
+		Scan(&channels).Error
+	if err != nil {
+		log.Printf("Erro ao listar DMs do usuário %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao buscar conversas."})
+		return
+	}
+
+	// Carrega os participantes de todas as conversas de uma vez
+	channelIDs := make([]uint, len(channels))
+	for i, channel := range channels {
+		channelIDs[i] = channel.ID
+	}
+	var withRecipients []models.Channel
+	if len(channelIDs) > 0 {
+		if err := dh.DB.Preload("Recipients").Where("id IN ?", channelIDs).Find(&withRecipients).Error; err != nil {
+			log.Printf("Erro ao carregar participantes dos DMs do usuário %d: %v", userID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao buscar conversas."})
+			return
+		}
+	}
+	recipientsByChannel := make(map[uint][]*models.User, len(withRecipients))
+	for _, channel := range withRecipients {
+		recipientsByChannel[channel.ID] = channel.Recipients
+	}
+	readStates, err := loadReadStates(dh.DB, userID, channelIDs)
+	if err != nil {
+		log.Printf("Erro ao carregar estado de leitura dos DMs do usuário %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao buscar conversas."})
+		return
+	}
+
+	channelResponses := []DMChannelResponse{}
+	for _, channel := range channels {
+		channel.Recipients = recipientsByChannel[channel.ID]
+		response := toDMChannelResponse(channel)
+		response.ChannelReadState = readStates[channel.ID]
+		channelResponses = append(channelResponses, response)
+	}
+	c.JSON(http.StatusOK, channelResponses)
+}
+
+// CreateDMChannel abre um DM com um usuário (reutilizando o existente) ou cria um DM em grupo.
+func (dh *DMHandler) CreateDMChannel(c *gin.Context) {
+	rawUserID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+	userID := rawUserID.(uint)
+
+	var req CreateDMRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos: " + err.Error()})
+		return
+	}
+
+	// Remove duplicatas e o próprio usuário da lista de destinatários
+	seen := map[uint]bool{userID: true}
+	var otherIDs []uint
+	for _, id := range req.RecipientIDs {
+		if !seen[id] {
+			seen[id] = true
+			otherIDs = append(otherIDs, id)
+		}
+	}
+	if len(otherIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Informe ao menos um destinatário diferente de você."})
+		return
+	}
+
+	var recipients []*models.User
+	if err := dh.DB.Where("id IN ?", append(otherIDs, userID)).Find(&recipients).Error; err != nil {
+		log.Printf("Erro ao buscar destinatários do DM: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao criar conversa."})
+		return
+	}
+	if len(recipients) != len(otherIDs)+1 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Usuário não encontrado"})
+		return
+	}
+
+	// Ninguém pode abrir conversa com quem o bloqueou (ou com quem bloqueou),
+	// nem reabrir um DM existente entre os dois
+	for _, otherID := range otherIDs {
+		blocked, err := isBlockedBetween(dh.DB, userID, otherID)
+		if err != nil {
+			log.Printf("Erro ao verificar bloqueio entre %d e %d: %v", userID, otherID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao criar conversa."})
+			return
+		}
+		if blocked {
+			respondAuthzError(c, errUserBlocked)
+			return
+		}
+	}
+
+	if len(otherIDs) == 1 && req.Name == "" {
+		// DM 1:1: reaproveita a conversa existente entre os dois usuários
+		channel, err := findDirectMessage(dh.DB, userID, otherIDs[0])
+		if err == nil {
+			c.JSON(http.StatusOK, toDMChannelResponse(*channel))
+			return
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("Erro ao buscar DM entre %d e %d: %v", userID, otherIDs[0], err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao criar conversa."})
+			return
+		}
+	}
+
+	channel := models.Channel{
+		ChannelType: ChannelTypeDM,
+		Recipients:  recipients,
+	}
+	if len(otherIDs) > 1 || req.Name != "" {
+		channel.ChannelType = ChannelTypeGroupDM
+		channel.ChannelName = req.Name
+		channel.OwnerID = &userID
+	}
+	if err := dh.DB.Create(&channel).Error; err != nil {
+		log.Printf("Erro ao criar DM para o usuário %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao criar conversa."})
+		return
+	}
+
+	response := toDMChannelResponse(channel)
+	dh.Hub.DispatchToUsers(recipientIDs(&channel), "channel_create", response)
+	c.JSON(http.StatusCreated, response)
+}
+
+// AddDMRecipient adiciona um usuário a um DM em grupo. Qualquer participante pode adicionar.
+func (dh *DMHandler) AddDMRecipient(c *gin.Context) {
+	rawUserID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+	userID := rawUserID.(uint)
+
+	channelID, ok := parseIDParam(c, "channelId")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID do canal inválido"})
+		return
+	}
+	targetID, ok := parseIDParam(c, "userId")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID do usuário inválido"})
+		return
+	}
+
+	channel, err := dh.authorizeGroupDM(c, userID, channelID)
+	if err != nil {
+		return
+	}
+	for _, recipient := range channel.Recipients {
+		if recipient.ID == targetID {
+			c.JSON(http.StatusOK, toDMChannelResponse(*channel)) // Já participa
+			return
+		}
+	}
+
+	var target models.User
+	if err := dh.DB.First(&target, targetID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Usuário não encontrado"})
+			return
+		}
+		log.Printf("Erro ao buscar usuário %d: %v", targetID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar usuário."})
+		return
+	}
+	blocked, err := isBlockedBetween(dh.DB, userID, target.ID)
+	if err != nil {
+		log.Printf("Erro ao verificar bloqueio entre %d e %d: %v", userID, target.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao adicionar participante."})
+		return
+	}
+	if blocked {
+		respondAuthzError(c, errUserBlocked)
+		return
+	}
+
+	err = dh.DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Table("channel_recipients").Where("channel_id = ?", channel.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count >= maxGroupDMRecipients {
+			return errGroupDMFull
+		}
+		return tx.Model(channel).Association("Recipients").Append(&target)
+	})
+	if err != nil {
+		if errors.Is(err, errGroupDMFull) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "O grupo atingiu o limite de participantes."})
+			return
+		}
+		log.Printf("Erro ao adicionar usuário %d ao DM %d: %v", targetID, channel.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao adicionar participante."})
+		return
+	}
+
+	payload := DMRecipientPayload{ChannelID: channel.ID, User: toUserSummary(target)}
+	dh.Hub.DispatchToUsers(recipientIDs(channel), "channel_recipient_add", payload)
+	response := toDMChannelResponse(*channel) // Association.Append já incluiu o novo participante
+	dh.Hub.DispatchToUsers([]uint{target.ID}, "channel_create", response)
+	c.JSON(http.StatusOK, response)
+}
+
+// RemoveDMRecipient remove um participante de um DM em grupo. O dono pode remover
+// qualquer um; os demais só podem sair. Se o dono sair, outro participante herda o grupo.
+func (dh *DMHandler) RemoveDMRecipient(c *gin.Context) {
+	rawUserID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+	userID := rawUserID.(uint)
+
+	channelID, ok := parseIDParam(c, "channelId")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID do canal inválido"})
+		return
+	}
+	targetID, ok := parseIDParam(c, "userId")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID do usuário inválido"})
+		return
+	}
+
+	channel, err := dh.authorizeGroupDM(c, userID, channelID)
+	if err != nil {
+		return
+	}
+	isOwner := channel.OwnerID != nil && *channel.OwnerID == userID
+	if targetID != userID && !isOwner {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Apenas o dono do grupo pode remover participantes."})
+		return
+	}
+
+	var target *models.User
+	var remaining []uint
+	for _, recipient := range channel.Recipients {
+		if recipient.ID == targetID {
+			target = recipient
+		} else {
+			remaining = append(remaining, recipient.ID)
+		}
+	}
+	if target == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Participante não encontrado"})
+		return
+	}
+
+	err = dh.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(channel).Association("Recipients").Delete(target); err != nil {
+			return err
+		}
+		if len(remaining) == 0 {
+			return tx.Delete(channel).Error // Último participante saiu
+		}
+		if channel.OwnerID != nil && *channel.OwnerID == targetID {
+			channel.OwnerID = &remaining[0]
+			return tx.Model(channel).Update("owner_id", remaining[0]).Error
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Erro ao remover usuário %d do DM %d: %v", targetID, channel.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao remover participante."})
+		return
+	}
+
+	dh.Hub.UnsubscribeUser(targetID, channel.ID)
+	payload := DMRecipientPayload{ChannelID: channel.ID, User: toUserSummary(*target)}
+	dh.Hub.DispatchToUsers(append(remaining, targetID), "channel_recipient_remove", payload)
+	c.Status(http.StatusNoContent)
+}
+
+// authorizeGroupDM carrega o DM em grupo e garante que o usuário participa dele.
+// Em caso de erro a resposta HTTP já foi escrita.
+func (dh *DMHandler) authorizeGroupDM(c *gin.Context, userID, channelID uint) (*models.Channel, error) {
+	channel, err := findDMChannel(dh.DB, channelID)
+	if err == nil {
+		err = errChannelNotFound
+		for _, recipient := range channel.Recipients {
+			if recipient.ID == userID {
+				err = nil
+				break
+			}
+		}
+	}
+	if err != nil {
+		respondAuthzError(c, err)
+		return nil, err
+	}
+	if channel.ChannelType != ChannelTypeGroupDM {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Participantes só podem ser alterados em DMs em grupo."})
+		return nil, errMissingPermission
+	}
+	return channel, nil
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+)
+
+// GatewayVersion é a versão atual do protocolo do gateway (/ws/chat?v=1).
+const GatewayVersion = 1
+
+// Opcodes do gateway.
+const (
+	OpDispatch       = 0  // Servidor -> cliente: evento com tipo (t) e número de sequência (s)
+	OpHeartbeat      = 1  // Cliente -> servidor: heartbeat com o último s recebido em d
+	OpIdentify       = 2  // Cliente -> servidor: inicia uma nova sessão
+	OpCommand        = 3  // Cliente -> servidor: comando (t = join_channel, new_message, ...)
+	OpResume         = 6  // Cliente -> servidor: retoma uma sessão e recebe os eventos perdidos
+	OpInvalidSession = 9  // Servidor -> cliente: sessão não pode ser retomada; envie IDENTIFY
+	OpHello          = 10 // Servidor -> cliente: primeiro frame, com o intervalo de heartbeat
+	OpHeartbeatACK   = 11 // Servidor -> cliente: confirmação de heartbeat
+)
+
+const (
+	// heartbeatInterval é o intervalo anunciado no HELLO.
+	heartbeatInterval = 30 * time.Second
+	// heartbeatTimeout é o tempo máximo sem receber frames do cliente antes de
+	// considerá-lo uma conexão zumbi e encerrá-la.
+	heartbeatTimeout = heartbeatInterval * 3 / 2
+	// sessionBufferSize é a quantidade máxima de eventos guardados por sessão para RESUME.
+	sessionBufferSize = 500
+	// sessionResumeWindow é por quanto tempo uma sessão desconectada pode ser retomada.
+	sessionResumeWindow = 2 * time.Minute
+)
+
+// GatewayPayload é o envelope de todos os frames do gateway.
+type GatewayPayload struct {
+	Op int             `json:"op"`
+	D  json.RawMessage `json:"d,omitempty"`
+	S  uint64          `json:"s,omitempty"`
+	T  string          `json:"t,omitempty"`
+}
+
+// HelloPayload é o conteúdo do frame HELLO.
+type HelloPayload struct {
+	HeartbeatInterval int64 `json:"heartbeatInterval"` // Em milissegundos
+	Version           int   `json:"version"`
+}
+
+// ResumePayload é o conteúdo do frame RESUME enviado pelo cliente.
+type ResumePayload struct {
+	SessionID string `json:"sessionId"`
+	Seq       uint64 `json:"seq"`
+}
+
+// ReadyPayload é o conteúdo do evento "ready", enviado após um IDENTIFY.
+type ReadyPayload struct {
+	SessionID string `json:"sessionId"`
+	UserID    uint   `json:"userId"`
+	Version   int    `json:"version"`
+}
+
+// encodeGatewayFrame serializa um frame completo do gateway.
+func encodeGatewayFrame(op int, eventType string, seq uint64, d interface{}) []byte {
+	var raw json.RawMessage
+	if d != nil {
+		var err error
+		if raw, err = json.Marshal(d); err != nil {
+			log.Printf("Erro ao serializar payload do gateway (op %d, t %q): %v", op, eventType, err)
+			return nil
+		}
+	}
+	data, err := json.Marshal(GatewayPayload{Op: op, D: raw, S: seq, T: eventType})
+	if err != nil {
+		log.Printf("Erro ao serializar frame do gateway (op %d, t %q): %v", op, eventType, err)
+		return nil
+	}
+	return data
+}
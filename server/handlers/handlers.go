@@ -2,18 +2,16 @@ package handlers
 
 import (
 	"errors"
-	"io"
 	"log"
 	"net/http"
 	"os"
-	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/google/uuid"
 	"github.com/gustavoverneck/discordia/server/models" // Certifique-se que este caminho está correto
+	"github.com/gustavoverneck/discordia/server/storage"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
@@ -92,12 +90,14 @@ func ValidateJWT(tokenString string) (*Claims, error) {
 
 // UserHandler agrupa os handlers relacionados a usuários.
 type UserHandler struct {
-	DB *gorm.DB
+	DB      *gorm.DB
+	Hub     *Hub            // Usado para avisar quem vê o usuário sobre mudanças no perfil
+	Storage storage.Storage // Onde ícones, avatares e banners são gravados
 }
 
 // NewUserHandler é o construtor para UserHandler.
-func NewUserHandler(db *gorm.DB) *UserHandler {
-	return &UserHandler{DB: db}
+func NewUserHandler(db *gorm.DB, hub *Hub, store storage.Storage) *UserHandler {
+	return &UserHandler{DB: db, Hub: hub, Storage: store}
 }
 
 // Register lida com o registro de novos usuários.
@@ -264,7 +264,7 @@ func (uh *UserHandler) Profile(c *gin.Context) {
 	var user models.User
 	// Buscar o usuário completo pelo ID obtido do token
 	// Selecionar campos para não expor PasswordHash acidentalmente se não usar DTO.
-	result := uh.DB.Select("id", "username", "email", "avatar_url", "status", "created_at", "updated_at").First(&user, userID)
+	result := uh.DB.Omit("password_hash").First(&user, userID)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Usuário não encontrado."})
@@ -275,15 +275,7 @@ func (uh *UserHandler) Profile(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"id":        user.ID,
-		"username":  user.Username,
-		"email":     user.Email,
-		"avatarURL": user.AvatarURL,
-		"status":    user.Status,
-		"createdAt": user.CreatedAt,
-		"updatedAt": user.UpdatedAt,
-	})
+	c.JSON(http.StatusOK, ownProfileResponse(user))
 }
 
 // --- Auth Middleware ---
@@ -297,12 +289,11 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+		tokenString, ok := extractBearerToken(authHeader)
+		if !ok {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Formato do cabeçalho de autorização inválido. Use 'Bearer <token>'."})
 			return
 		}
-		tokenString := parts[1]
 
 		claims, err := ValidateJWT(tokenString)
 		if err != nil {
@@ -321,9 +312,12 @@ func AuthMiddleware() gin.HandlerFunc {
 type UpdateProfileRequest struct {
 	Username string `json:"username" binding:"omitempty,min=3,max=32"` // omitempty permite não enviar se não quiser mudar
 	Email    string `json:"email" binding:"omitempty,email"`
-	// Adicione outros campos que podem ser atualizados, ex: AvatarURL, Status
-	// AvatarURL string `json:"avatarURL" binding:"omitempty,url"`
-	// Status      string `json:"status" binding:"omitempty,min=2"`
+	// Campos do perfil público: ausente = não muda, "" = limpa.
+	// Avatar e banner são enviados em PUT /profile/avatar e /profile/banner.
+	DisplayName *string `json:"displayName" binding:"omitempty,max=32"`
+	Bio         *string `json:"bio" binding:"omitempty,max=190"`
+	Pronouns    *string `json:"pronouns" binding:"omitempty,max=40"`
+	AccentColor *string `json:"accentColor"` // #rrggbb
 }
 
 // UpdateProfile permite que um usuário autenticado atualize seu próprio perfil
@@ -380,9 +374,23 @@ func (uh *UserHandler) UpdateProfile(c *gin.Context) {
 		user.Email = normalizedEmail // Atualiza o email
 	}
 
-	// Atualizar outros campos se eles foram incluídos no request e permitidos
-	// Ex: if req.AvatarURL != "" { user.AvatarURL = req.AvatarURL }
-	// Ex: if req.Status != "" { user.Status = req.Status }
+	// Campos do perfil público
+	if req.DisplayName != nil {
+		user.DisplayName = strings.TrimSpace(*req.DisplayName)
+	}
+	if req.Bio != nil {
+		user.Bio = strings.TrimSpace(*req.Bio)
+	}
+	if req.Pronouns != nil {
+		user.Pronouns = strings.TrimSpace(*req.Pronouns)
+	}
+	if req.AccentColor != nil {
+		if *req.AccentColor != "" && !accentColorPattern.MatchString(*req.AccentColor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cor de destaque inválida. Use o formato #rrggbb."})
+			return
+		}
+		user.AccentColor = strings.ToLower(*req.AccentColor)
+	}
 
 	// Salvar as alterações no banco de dados
 	if result := uh.DB.Save(&user); result.Error != nil {
@@ -390,18 +398,11 @@ func (uh *UserHandler) UpdateProfile(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao salvar as alterações do perfil."})
 		return
 	}
+	uh.dispatchUserUpdate(user)
 
 	// Retornar o perfil atualizado (sem o hash da senha)
 	// Reutilizando a mesma estrutura de resposta do GET /profile para consistência
-	c.JSON(http.StatusOK, gin.H{
-		"id":        user.ID,
-		"username":  user.Username,
-		"email":     user.Email,
-		"avatarURL": user.AvatarURL,
-		"status":    user.Status,
-		"CreatedAt": user.CreatedAt,
-		"UpdatedAt": user.UpdatedAt,
-	})
+	c.JSON(http.StatusOK, ownProfileResponse(user))
 }
 
 func (uh *UserHandler) ListUserServers(c *gin.Context) {
@@ -440,14 +441,23 @@ func (uh *UserHandler) ListUserServers(c *gin.Context) {
 		ID         uint   `json:"id"`
 		ServerName string `json:"name"`
 		IconURL    string `json:"iconUrl,omitempty"`
+		ServerReadState
+	}
+
+	readStates, err := serverReadStates(uh.DB, userID, userWithServers.MemberServers)
+	if err != nil {
+		log.Printf("Erro ao carregar estado de leitura dos servidores do usuário %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar servidores do usuário"})
+		return
 	}
 
 	var serverResponses []ServerResponse
 	for _, server := range userWithServers.MemberServers {
 		serverResponses = append(serverResponses, ServerResponse{
-			ID:         server.ID,
-			ServerName: server.ServerName,
-			IconURL:    server.IconURL, // Certifique-se que seu models.Server tem IconURL
+			ID:              server.ID,
+			ServerName:      server.ServerName,
+			IconURL:         server.IconURL, // Certifique-se que seu models.Server tem IconURL
+			ServerReadState: readStates[server.ID],
 		})
 	}
 
@@ -476,74 +486,19 @@ func (uh *UserHandler) CreateServer(c *gin.Context) {
 
 	fileHeader, err := c.FormFile("iconFile")
 	if err == nil && fileHeader != nil {
-		file, openErr := fileHeader.Open()
-		if openErr != nil {
-			log.Printf("Erro ao abrir arquivo de upload: %v", openErr)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao processar o ícone."})
-			return
-		}
-		defer file.Close()
-
-		// Validações de tamanho e tipo (como no exemplo anterior)
-		if fileHeader.Size > 5*1024*1024 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Arquivo de ícone muito grande (máx 5MB)."})
-			return
-		}
-		buffer := make([]byte, 512)
-		_, readErr := file.Read(buffer)
-		if readErr != nil && readErr != io.EOF {
-			log.Printf("Erro ao ler buffer do arquivo: %v", readErr)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao processar o tipo do ícone."})
-			return
-		}
-		_, seekErr := file.Seek(0, io.SeekStart)
-		if seekErr != nil {
-			log.Printf("Erro ao fazer seek no arquivo: %v", seekErr)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao processar o ícone."})
-			return
-		}
-		contentType := http.DetectContentType(buffer)
-		if !strings.HasPrefix(contentType, "image/") {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Tipo de arquivo inválido. Apenas imagens são permitidas."})
-			return
-		}
-
-		ext := filepath.Ext(fileHeader.Filename)
-		if ext == "" {
-			switch contentType {
-			case "image/jpeg":
-				ext = ".jpg"
-			case "image/png":
-				ext = ".png"
-			case "image/gif":
-				ext = ".gif"
-			default:
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Formato de imagem não suportado para extensão automática."})
+		// A imagem é validada, limpa de metadados e gravada em vários tamanhos
+		iconURL, storeErr := storeImage(c.Request.Context(), uh.Storage, serverIconImage, fileHeader)
+		if storeErr != nil {
+			if message, ok := imageErrorMessage(storeErr); ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": message})
 				return
 			}
-		}
-		uniqueFilename := uuid.New().String() + ext
-		uploadsDir := "./uploads/server_icons"
-		if _, statErr := os.Stat(uploadsDir); os.IsNotExist(statErr) {
-			os.MkdirAll(uploadsDir, os.ModePerm)
-		}
-		filePath := filepath.Join(uploadsDir, uniqueFilename)
-
-		out, createErr := os.Create(filePath)
-		if createErr != nil {
-			log.Printf("Erro ao criar arquivo no servidor para %s: %v", filePath, createErr)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao salvar o ícone do servidor."})
-			return
-		}
-		defer out.Close()
-		_, copyErr := io.Copy(out, file)
-		if copyErr != nil {
-			log.Printf("Erro ao copiar conteúdo do arquivo para %s: %v", filePath, copyErr)
+			log.Printf("Erro ao armazenar ícone do servidor: %v", storeErr)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao armazenar o ícone do servidor."})
 			return
 		}
-		iconFileLocationInDB = "/static/server_icons/" + uniqueFilename
-		log.Printf("Ícone salvo em: %s, URL relativa para DB: %s", filePath, iconFileLocationInDB)
+		iconFileLocationInDB = iconURL
+		log.Printf("Ícone salvo, URL relativa para DB: %s", iconFileLocationInDB)
 
 	} else if err != nil && !errors.Is(err, http.ErrMissingFile) {
 		log.Printf("Erro ao processar FormFile 'iconFile': %v", err)
@@ -572,14 +527,9 @@ func (uh *UserHandler) CreateServer(c *gin.Context) {
 	}
 
 	// ---- INÍCIO DA MUDANÇA PARA ADICIONAR MEMBRO ----
-	// Adicionar o criador (owner) como membro do servidor usando a associação many2many do GORM.
-	// Precisamos de uma instância de models.User (pode ser apenas com o ID) para a associação.
-	ownerUser := models.User{}
-	ownerUser.ID = userID // O GORM usa o ID para criar a entrada na tabela de junção.
-
-	// Associa o ownerUser à lista de Members do newServer.
-	// GORM irá inserir um registro na tabela 'server_members' (user_id, server_id).
-	if err := tx.Model(&newServer).Association("Members").Append(&ownerUser); err != nil {
+	// Adicionar o criador (owner) como membro do servidor.
+	// Insere um registro na tabela 'server_members' (models.ServerMember).
+	if err := tx.Create(&models.ServerMember{ServerID: newServer.ID, UserID: userID}).Error; err != nil {
 		tx.Rollback()
 		log.Printf("Erro ao associar owner (%d) como membro do servidor (%d): %v", userID, newServer.ID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao configurar proprietário como membro do servidor."})
@@ -587,6 +537,20 @@ func (uh *UserHandler) CreateServer(c *gin.Context) {
 	}
 	// ---- FIM DA MUDANÇA PARA ADICIONAR MEMBRO ----
 
+	// Cargo @everyone com as permissões padrão de todos os membros.
+	everyone := models.Role{
+		ServerID:    newServer.ID,
+		Name:        "@everyone",
+		Permissions: DefaultEveryonePermissions,
+		IsDefault:   true,
+	}
+	if err := tx.Create(&everyone).Error; err != nil {
+		tx.Rollback()
+		log.Printf("Erro ao criar cargo @everyone do servidor %d: %v", newServer.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao configurar cargos do servidor."})
+		return
+	}
+
 	if err := tx.Commit().Error; err != nil {
 		log.Printf("Erro ao commitar transação de criar servidor: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro final ao criar servidor."})
@@ -0,0 +1,504 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+)
+
+// subscription representa o pedido de um cliente para receber os eventos de um canal.
+type subscription struct {
+	client    *Client
+	channelID uint
+}
+
+// channelBroadcast é um evento destinado a todas as sessões inscritas em um canal.
+// O payload já vem serializado para que seja codificado uma única vez.
+type channelBroadcast struct {
+	channelID uint
+	eventType string
+	d         json.RawMessage
+}
+
+// userDispatch é um evento destinado a todas as sessões de um conjunto de usuários.
+type userDispatch struct {
+	userIDs   []uint
+	eventType string
+	d         json.RawMessage
+}
+
+// userSubscription identifica todas as sessões de um usuário em um canal.
+type userSubscription struct {
+	userID    uint
+	channelID uint
+}
+
+// clientDispatch é um evento destinado apenas à sessão de um cliente.
+type clientDispatch struct {
+	client    *Client
+	eventType string
+	d         interface{}
+}
+
+type identifyRequest struct {
+	client *Client
+	status string // Status escolhido pelo usuário (models.User.Status)
+	reply  chan bool
+}
+
+// sessionActivity registra atividade (ou ausência informada pelo cliente) em uma sessão.
+type sessionActivity struct {
+	client *Client
+	afk    bool
+}
+
+type statusUpdate struct {
+	userID uint
+	status string
+}
+
+type presenceQuery struct {
+	userIDs []uint
+	reply   chan map[uint]string
+}
+
+type resumeRequest struct {
+	client    *Client
+	sessionID string
+	seq       uint64
+	reply     chan bool
+}
+
+// Hub é o único dono do estado das conexões e sessões do gateway.
+// Registro, remoção, IDENTIFY/RESUME, inscrição em canais e fan-out passam
+// por channels e são processados sequencialmente pela goroutine de Run,
+// então nenhum mapa é acessado concorrentemente.
+type Hub struct {
+	// clients mapeia cada conexão registrada para sua sessão (nil antes de IDENTIFY/RESUME).
+	clients map[*Client]*Session
+	// sessions mapeia sessionID -> sessão, conectada ou aguardando RESUME.
+	sessions map[string]*Session
+	// channels mapeia channelID -> sessões inscritas.
+	channels map[uint]map[*Session]bool
+	// users mapeia userID -> sessões do usuário (um usuário pode ter vários dispositivos).
+	users map[uint]map[*Session]bool
+	// statuses guarda o status escolhido por cada usuário com sessões.
+	statuses map[uint]string
+	// presences guarda a última presença publicada de cada usuário que não está offline.
+	presences map[uint]string
+	// presence recebe as mudanças de presença, publicadas pelo PresenceHandler.
+	presence *presenceQueue
+	// typing guarda os indicadores de digitação ativos.
+	typing map[typingKey]typingState
+
+	register    chan *Client
+	unregister  chan *Client
+	identify    chan identifyRequest
+	resume      chan resumeRequest
+	subscribe   chan subscription
+	unsubscribe chan subscription
+	broadcast   chan channelBroadcast
+	send        chan clientDispatch
+
+	userBroadcast   chan userDispatch
+	userUnsubscribe chan userSubscription
+
+	activity      chan sessionActivity
+	statusUpdate  chan statusUpdate
+	presenceQuery chan presenceQuery
+
+	typingStart chan typingKey
+	typingStop  chan typingKey
+}
+
+// NewHub é o construtor para Hub. Lembre-se de iniciar hub.Run() em uma goroutine.
+func NewHub() *Hub {
+	return &Hub{
+		clients:     make(map[*Client]*Session),
+		sessions:    make(map[string]*Session),
+		channels:    make(map[uint]map[*Session]bool),
+		users:       make(map[uint]map[*Session]bool),
+		statuses:    make(map[uint]string),
+		presences:   make(map[uint]string),
+		presence:    newPresenceQueue(),
+		typing:      make(map[typingKey]typingState),
+		register:    make(chan *Client),
+		unregister:  make(chan *Client),
+		identify:    make(chan identifyRequest),
+		resume:      make(chan resumeRequest),
+		subscribe:   make(chan subscription),
+		unsubscribe: make(chan subscription),
+		broadcast:   make(chan channelBroadcast, 256),
+		send:        make(chan clientDispatch, 256),
+
+		userBroadcast:   make(chan userDispatch, 256),
+		userUnsubscribe: make(chan userSubscription),
+
+		activity:      make(chan sessionActivity, 256),
+		statusUpdate:  make(chan statusUpdate),
+		presenceQuery: make(chan presenceQuery),
+
+		typingStart: make(chan typingKey, 256),
+		typingStop:  make(chan typingKey, 256),
+	}
+}
+
+// Run processa os eventos do hub. Deve rodar em uma única goroutine.
+func (h *Hub) Run() {
+	cleanup := time.NewTicker(sessionResumeWindow / 4)
+	defer cleanup.Stop()
+	typingSweep := time.NewTicker(typingSweepInterval)
+	defer typingSweep.Stop()
+
+	for {
+		select {
+		case client := <-h.register:
+			h.clients[client] = nil
+
+		case client := <-h.unregister:
+			h.dropClient(client)
+
+		case req := <-h.identify:
+			req.reply <- h.handleIdentify(req.client, req.status)
+
+		case req := <-h.resume:
+			req.reply <- h.handleResume(req)
+
+		case sub := <-h.subscribe:
+			session := h.clients[sub.client]
+			if session == nil {
+				continue // Cliente removido ou ainda não identificado
+			}
+			if h.channels[sub.channelID] == nil {
+				h.channels[sub.channelID] = make(map[*Session]bool)
+			}
+			h.channels[sub.channelID][session] = true
+			session.channels[sub.channelID] = true
+
+		case sub := <-h.unsubscribe:
+			if session := h.clients[sub.client]; session != nil {
+				delete(session.channels, sub.channelID)
+				h.removeFromChannel(session, sub.channelID)
+			}
+
+		case msg := <-h.broadcast:
+			for session := range h.channels[msg.channelID] {
+				h.dispatch(session, msg.eventType, msg.d)
+			}
+
+		case msg := <-h.userBroadcast:
+			for _, userID := range msg.userIDs {
+				for session := range h.users[userID] {
+					h.dispatch(session, msg.eventType, msg.d)
+				}
+			}
+
+		case sub := <-h.userUnsubscribe:
+			for session := range h.users[sub.userID] {
+				delete(session.channels, sub.channelID)
+				h.removeFromChannel(session, sub.channelID)
+			}
+
+		case act := <-h.activity:
+			if session := h.clients[act.client]; session != nil {
+				session.afk = act.afk
+				if !act.afk {
+					session.lastActivity = time.Now()
+				}
+				h.refreshPresence(session.userID)
+			}
+
+		case update := <-h.statusUpdate:
+			if h.users[update.userID] != nil {
+				h.statuses[update.userID] = update.status
+				h.refreshPresence(update.userID)
+			}
+
+		case query := <-h.presenceQuery:
+			statuses := make(map[uint]string, len(query.userIDs))
+			for _, userID := range query.userIDs {
+				statuses[userID] = PresenceOffline
+				if status, ok := h.presences[userID]; ok {
+					statuses[userID] = status
+				}
+			}
+			query.reply <- statuses
+
+		case key := <-h.typingStart:
+			h.handleTypingStart(key, time.Now())
+
+		case key := <-h.typingStop:
+			h.handleTypingStop(key)
+
+		case now := <-typingSweep.C:
+			h.expireTyping(now)
+
+		case msg := <-h.send:
+			session, registered := h.clients[msg.client]
+			if !registered {
+				continue
+			}
+			if session != nil {
+				h.dispatch(session, msg.eventType, msg.d)
+			} else if data := encodeGatewayFrame(OpDispatch, msg.eventType, 0, msg.d); data != nil {
+				// Sem sessão ainda: o evento vai sem número de sequência.
+				msg.client.enqueue(data)
+			}
+
+		case now := <-cleanup.C:
+			for _, session := range h.sessions {
+				if session.client == nil && now.Sub(session.detachedAt) > sessionResumeWindow {
+					h.destroySession(session)
+				}
+			}
+			// Sessões sem atividade recente passam a contar como ausentes
+			for userID := range h.users {
+				h.refreshPresence(userID)
+			}
+		}
+	}
+}
+
+func (h *Hub) handleIdentify(client *Client, status string) bool {
+	current, registered := h.clients[client]
+	if !registered || current != nil {
+		return false
+	}
+	session := newSession(client.userID)
+	h.sessions[session.ID] = session
+	if h.users[session.userID] == nil {
+		h.users[session.userID] = make(map[*Session]bool)
+	}
+	h.users[session.userID][session] = true
+	h.statuses[session.userID] = status
+	h.attach(session, client)
+	h.dispatch(session, "ready", ReadyPayload{SessionID: session.ID, UserID: client.userID, Version: GatewayVersion})
+	return true
+}
+
+func (h *Hub) handleResume(req resumeRequest) bool {
+	current, registered := h.clients[req.client]
+	if !registered || current != nil {
+		return false
+	}
+	session, ok := h.sessions[req.sessionID]
+	if !ok || session.userID != req.client.userID {
+		return false
+	}
+	missed, ok := session.eventsAfter(req.seq)
+	if !ok {
+		// Os eventos perdidos já saíram do buffer; a sessão não pode mais ser retomada.
+		h.destroySession(session)
+		return false
+	}
+
+	if old := session.client; old != nil {
+		// A conexão antiga (provavelmente zumbi) é substituída pela nova.
+		h.clients[old] = nil
+		old.closeSend()
+	}
+	h.attach(session, req.client)
+	for _, ev := range missed {
+		if !req.client.enqueue(ev.data) {
+			h.dropClient(req.client)
+			return false
+		}
+	}
+	h.dispatch(session, "resumed", nil)
+	return true
+}
+
+func (h *Hub) attach(session *Session, client *Client) {
+	session.client = client
+	session.lastActivity = time.Now()
+	h.clients[client] = session
+	h.refreshPresence(session.userID)
+}
+
+// dispatch envia um evento numerado para a sessão. Se a sessão estiver
+// desconectada, o evento fica apenas no buffer de replay.
+func (h *Hub) dispatch(session *Session, eventType string, d interface{}) {
+	data := session.nextFrame(eventType, d)
+	if data == nil || session.client == nil {
+		return
+	}
+	if !session.client.enqueue(data) {
+		// Fila cheia: o cliente lento é desconectado para não travar os demais.
+		// A sessão continua disponível para RESUME.
+		log.Printf("Cliente %p (usuário %d) não acompanha o ritmo de envio; desconectando", session.client, session.userID)
+		h.dropClient(session.client)
+	}
+}
+
+// dropClient remove a conexão do hub e fecha sua fila de envio, o que faz a
+// goroutine de escrita encerrar o socket. A sessão fica desconectada, mas
+// continua inscrita e guardando eventos até expirar a janela de RESUME.
+func (h *Hub) dropClient(client *Client) {
+	session, ok := h.clients[client]
+	if !ok {
+		return
+	}
+	delete(h.clients, client)
+	if session != nil && session.client == client {
+		session.client = nil
+		session.detachedAt = time.Now()
+		h.refreshPresence(session.userID)
+	}
+	client.closeSend()
+}
+
+// destroySession remove definitivamente a sessão e suas inscrições.
+func (h *Hub) destroySession(session *Session) {
+	for channelID := range session.channels {
+		h.removeFromChannel(session, channelID)
+	}
+	if session.client != nil {
+		h.clients[session.client] = nil
+		session.client.closeSend()
+	}
+	delete(h.sessions, session.ID)
+	if userSessions := h.users[session.userID]; userSessions != nil {
+		delete(userSessions, session)
+		if len(userSessions) == 0 {
+			delete(h.users, session.userID)
+			delete(h.statuses, session.userID)
+		}
+	}
+	h.refreshPresence(session.userID)
+}
+
+// computePresence calcula a presença visível de um usuário a partir de todas as
+// suas sessões conectadas: basta uma sessão ativa para ele aparecer online.
+func (h *Hub) computePresence(userID uint, now time.Time) string {
+	connected, active := false, false
+	for session := range h.users[userID] {
+		if session.client == nil {
+			continue
+		}
+		connected = true
+		if !session.afk && now.Sub(session.lastActivity) < presenceIdleTimeout {
+			active = true
+		}
+	}
+	if !connected {
+		return PresenceOffline
+	}
+	switch h.statuses[userID] {
+	case PresenceInvisible:
+		return PresenceOffline
+	case PresenceDND:
+		return PresenceDND
+	case PresenceIdle:
+		return PresenceIdle
+	}
+	if active {
+		return PresenceOnline
+	}
+	return PresenceIdle
+}
+
+// refreshPresence recalcula a presença do usuário e a publica se mudou.
+func (h *Hub) refreshPresence(userID uint) {
+	status := h.computePresence(userID, time.Now())
+	previous, ok := h.presences[userID]
+	if !ok {
+		previous = PresenceOffline
+	}
+	if status == previous {
+		return
+	}
+	if status == PresenceOffline {
+		delete(h.presences, userID)
+	} else {
+		h.presences[userID] = status
+	}
+	h.presence.push(presenceChange{userID: userID, status: status})
+}
+
+func (h *Hub) removeFromChannel(session *Session, channelID uint) {
+	sessionsInChannel, ok := h.channels[channelID]
+	if !ok {
+		return
+	}
+	delete(sessionsInChannel, session)
+	if len(sessionsInChannel) == 0 {
+		delete(h.channels, channelID)
+	}
+}
+
+// Identify cria uma nova sessão para o cliente e envia o evento "ready".
+// status é o status escolhido pelo usuário, usado no cálculo da presença.
+func (h *Hub) Identify(client *Client, status string) bool {
+	reply := make(chan bool, 1)
+	h.identify <- identifyRequest{client: client, status: status, reply: reply}
+	return <-reply
+}
+
+// Resume associa o cliente a uma sessão existente e reenvia os eventos após seq.
+// Retorna false se a sessão não existe, pertence a outro usuário ou não pode ser retomada.
+func (h *Hub) Resume(client *Client, sessionID string, seq uint64) bool {
+	reply := make(chan bool, 1)
+	h.resume <- resumeRequest{client: client, sessionID: sessionID, seq: seq, reply: reply}
+	return <-reply
+}
+
+// Subscribe inscreve a sessão do cliente no canal informado.
+func (h *Hub) Subscribe(client *Client, channelID uint) {
+	h.subscribe <- subscription{client: client, channelID: channelID}
+}
+
+// Unsubscribe remove a inscrição da sessão do cliente no canal informado.
+func (h *Hub) Unsubscribe(client *Client, channelID uint) {
+	h.unsubscribe <- subscription{client: client, channelID: channelID}
+}
+
+// BroadcastToChannel serializa v uma única vez e o despacha como evento
+// eventType para todas as sessões inscritas no canal.
+func (h *Hub) BroadcastToChannel(channelID uint, eventType string, v interface{}) {
+	d, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("Erro ao serializar evento %s para o canal %d: %v", eventType, channelID, err)
+		return
+	}
+	h.broadcast <- channelBroadcast{channelID: channelID, eventType: eventType, d: d}
+}
+
+// SendToClient despacha um evento apenas para a sessão do cliente.
+func (h *Hub) SendToClient(client *Client, eventType string, v interface{}) {
+	h.send <- clientDispatch{client: client, eventType: eventType, d: v}
+}
+
+// DispatchToUsers serializa v uma única vez e o despacha como evento eventType
+// para todas as sessões dos usuários informados, independente de inscrições em canais.
+func (h *Hub) DispatchToUsers(userIDs []uint, eventType string, v interface{}) {
+	d, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("Erro ao serializar evento %s para usuários: %v", eventType, err)
+		return
+	}
+	h.userBroadcast <- userDispatch{userIDs: userIDs, eventType: eventType, d: d}
+}
+
+// UnsubscribeUser remove as inscrições de todas as sessões do usuário no canal,
+// ex: quando ele perde acesso ao canal.
+func (h *Hub) UnsubscribeUser(userID, channelID uint) {
+	h.userUnsubscribe <- userSubscription{userID: userID, channelID: channelID}
+}
+
+// MarkActive registra atividade na sessão do cliente, ou que o cliente está
+// ausente (afk), para o cálculo de presença.
+func (h *Hub) MarkActive(client *Client, afk bool) {
+	h.activity <- sessionActivity{client: client, afk: afk}
+}
+
+// SetStatus atualiza o status escolhido pelo usuário em suas sessões abertas.
+func (h *Hub) SetStatus(userID uint, status string) {
+	h.statusUpdate <- statusUpdate{userID: userID, status: status}
+}
+
+// Presences retorna a presença atual de cada usuário informado.
+func (h *Hub) Presences(userIDs []uint) map[uint]string {
+	reply := make(chan map[uint]string, 1)
+	h.presenceQuery <- presenceQuery{userIDs: userIDs, reply: reply}
+	return <-reply
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime/multipart"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/gustavoverneck/discordia/server/imaging"
+	"github.com/gustavoverneck/discordia/server/storage"
+)
+
+const maxImageUploadSize = 5 * 1024 * 1024 // Tamanho máximo de ícones, avatares e banners enviados
+
+// imageKind descreve como um tipo de imagem é recortado e em quais larguras é
+// gravado. A URL gravada aponta para a maior; as demais são pedidas com ?size=N.
+type imageKind struct {
+	prefix           string // Prefixo das chaves no armazenamento
+	aspectW, aspectH int    // Proporção do recorte
+	widths           []int  // Em ordem crescente
+}
+
+var (
+	serverIconImage = imageKind{prefix: "server_icons", aspectW: 1, aspectH: 1, widths: []int{64, 128, 512}}
+	avatarImage     = imageKind{prefix: "avatars", aspectW: 1, aspectH: 1, widths: []int{64, 128, 512}}
+	bannerImage     = imageKind{prefix: "banners", aspectW: 5, aspectH: 2, widths: []int{300, 600, 960}}
+)
+
+// imageKinds indexa os tipos de imagem pelo prefixo da chave, para resolver ?size=N.
+var imageKinds = map[string]imageKind{
+	serverIconImage.prefix: serverIconImage,
+	avatarImage.prefix:     avatarImage,
+	bannerImage.prefix:     bannerImage,
+}
+
+var errImageTooLarge = errors.New("imagem muito grande (máx 5MB)")
+
+// storeImage processa a imagem enviada (metadados removidos, recorte e
+// variantes conforme kind) e grava todas as versões no backend.
+// Devolve a URL da maior versão, que é a gravada no banco.
+func storeImage(ctx context.Context, store storage.Storage, kind imageKind, fileHeader *multipart.FileHeader) (string, error) {
+	if fileHeader.Size > maxImageUploadSize {
+		return "", errImageTooLarge
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, maxImageUploadSize+1))
+	if err != nil {
+		return "", err
+	}
+	if len(data) > maxImageUploadSize {
+		return "", errImageTooLarge
+	}
+
+	variants, err := imaging.Crops(data, kind.aspectW, kind.aspectH, kind.widths)
+	if err != nil {
+		return "", err
+	}
+	largest := variants[len(variants)-1]
+	url, err := storeUpload(ctx, store, kind.prefix, largest.Ext, bytes.NewReader(largest.Data), int64(len(largest.Data)), largest.ContentType)
+	if err != nil {
+		return "", err
+	}
+	key := strings.TrimPrefix(url, uploadsURLPrefix)
+	for _, variant := range variants[:len(variants)-1] {
+		variantKey := imageVariantKey(key, variant.Size)
+		if err := store.Put(ctx, variantKey, bytes.NewReader(variant.Data), int64(len(variant.Data)), variant.ContentType); err != nil {
+			deleteImage(ctx, store, url)
+			return "", err
+		}
+	}
+	return url, nil
+}
+
+// deleteImage remove a imagem de uma URL gravada por storeImage e todas as suas variantes.
+func deleteImage(ctx context.Context, store storage.Storage, url string) {
+	if key, ok := strings.CutPrefix(url, uploadsURLPrefix); ok {
+		if kind, ok := imageKinds[path.Dir(key)]; ok {
+			for _, width := range kind.widths[:len(kind.widths)-1] {
+				deleteUpload(ctx, store, imageVariantURL(url, width))
+			}
+		}
+	}
+	deleteUpload(ctx, store, url)
+}
+
+// imageVariantKey devolve a chave da variante mais adequada para exibir a
+// imagem com a largura pedida: a menor que não fique borrada. Chaves sem
+// variantes são devolvidas sem alteração.
+func imageVariantKey(key string, size int) string {
+	kind, ok := imageKinds[path.Dir(key)]
+	if !ok {
+		return key
+	}
+	largest := kind.widths[len(kind.widths)-1]
+	chosen := largest
+	for _, s := range kind.widths {
+		if s >= size {
+			chosen = s
+			break
+		}
+	}
+	if chosen == largest {
+		return key
+	}
+	ext := path.Ext(key)
+	return strings.TrimSuffix(key, ext) + "_" + strconv.Itoa(chosen) + ext
+}
+
+// imageVariantURL é imageVariantKey para URLs gravadas no banco.
+func imageVariantURL(url string, size int) string {
+	key, ok := strings.CutPrefix(url, uploadsURLPrefix)
+	if !ok {
+		return url
+	}
+	return uploadsURLPrefix + imageVariantKey(key, size)
+}
+
+// imageErrorMessage traduz os erros de processamento de imagem para a resposta ao cliente.
+// Devolve false para erros internos.
+func imageErrorMessage(err error) (string, bool) {
+	switch {
+	case errors.Is(err, errImageTooLarge):
+		return err.Error(), true
+	case errors.Is(err, imaging.ErrUnsupportedFormat):
+		return "Tipo de arquivo inválido. Apenas imagens PNG, JPEG ou GIF são permitidas.", true
+	case errors.Is(err, imaging.ErrTooLarge):
+		return "Imagem com dimensões grandes demais.", true
+	case errors.Is(err, imaging.ErrInvalidImage):
+		return "Imagem inválida ou corrompida.", true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,372 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"errors"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gustavoverneck/discordia/server/models"
+	"gorm.io/gorm"
+)
+
+const (
+	inviteCodeLength    = 8
+	inviteCodeAlphabet  = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	defaultInviteMaxAge = 24 * 60 * 60 // 1 dia, em segundos
+)
+
+var errInviteUnavailable = errors.New("convite inválido ou expirado")
+
+type InviteHandler struct {
+	DB  *gorm.DB
+	Hub *Hub
+}
+
+func NewInviteHandler(db *gorm.DB, hub *Hub) *InviteHandler {
+	return &InviteHandler{DB: db, Hub: hub}
+}
+
+type CreateInviteRequest struct {
+	// MaxAgeSeconds: 0 = nunca expira; ausente = 1 dia
+	MaxAgeSeconds *int `json:"maxAgeSeconds" binding:"omitempty,min=0,max=604800"`
+	// MaxUses: 0 = ilimitado
+	MaxUses int `json:"maxUses" binding:"omitempty,min=0,max=100"`
+}
+
+type InviteResponse struct {
+	Code      string     `json:"code"`
+	ServerID  uint       `json:"serverId"`
+	CreatorID uint       `json:"creatorId"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	MaxUses   int        `json:"maxUses"`
+	Uses      int        `json:"uses"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+func toInviteResponse(invite models.Invite) InviteResponse {
+	return InviteResponse{
+		Code:      invite.Code,
+		ServerID:  invite.ServerID,
+		CreatorID: invite.CreatorID,
+		ExpiresAt: invite.ExpiresAt,
+		MaxUses:   invite.MaxUses,
+		Uses:      invite.Uses,
+		CreatedAt: invite.CreatedAt,
+	}
+}
+
+// generateInviteCode gera um código aleatório e legível para convites.
+func generateInviteCode() (string, error) {
+	code := make([]byte, inviteCodeLength)
+	alphabetSize := big.NewInt(int64(len(inviteCodeAlphabet)))
+	for i := range code {
+		n, err := rand.Int(rand.Reader, alphabetSize)
+		if err != nil {
+			return "", err
+		}
+		code[i] = inviteCodeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// inviteUsable informa se o convite ainda pode ser usado.
+func inviteUsable(invite *models.Invite, now time.Time) bool {
+	if invite.ExpiresAt != nil && now.After(*invite.ExpiresAt) {
+		return false
+	}
+	return invite.MaxUses == 0 || invite.Uses < invite.MaxUses
+}
+
+// findUsableInvite busca um convite pelo código, ignorando os expirados ou esgotados.
+func findUsableInvite(db *gorm.DB, code string) (*models.Invite, error) {
+	var invite models.Invite
+	if err := db.Where("code = ?", code).First(&invite).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errInviteUnavailable
+		}
+		return nil, err
+	}
+	if !inviteUsable(&invite, time.Now()) {
+		return nil, errInviteUnavailable
+	}
+	return &invite, nil
+}
+
+// CreateInvite cria um convite para o servidor. Requer a permissão CREATE_INVITE.
+func (ih *InviteHandler) CreateInvite(c *gin.Context) {
+	rawUserID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+	userID := rawUserID.(uint)
+
+	serverID, err := strconv.ParseUint(c.Param("serverId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID do servidor inválido"})
+		return
+	}
+
+	var req CreateInviteRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) { // Corpo vazio usa os padrões
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos: " + err.Error()})
+		return
+	}
+
+	if _, err := authorizeServerPermission(ih.DB, userID, uint(serverID), PermissionCreateInvite); err != nil {
+		respondAuthzError(c, err)
+		return
+	}
+
+	code, err := generateInviteCode()
+	if err != nil {
+		log.Printf("Erro ao gerar código de convite: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao gerar convite."})
+		return
+	}
+
+	maxAge := defaultInviteMaxAge
+	if req.MaxAgeSeconds != nil {
+		maxAge = *req.MaxAgeSeconds
+	}
+	invite := models.Invite{
+		Code:      code,
+		ServerID:  uint(serverID),
+		CreatorID: userID,
+		MaxUses:   req.MaxUses,
+	}
+	if maxAge > 0 {
+		expiresAt := time.Now().Add(time.Duration(maxAge) * time.Second)
+		invite.ExpiresAt = &expiresAt
+	}
+
+	if err := ih.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&invite).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, auditEntry{
+			ServerID:   invite.ServerID,
+			ActorID:    userID,
+			Action:     AuditInviteCreate,
+			TargetType: AuditTargetInvite,
+			TargetID:   invite.ID,
+			Changes:    auditDiff(nil, inviteAuditFields(invite)),
+			Reason:     auditReason(c),
+		})
+	}); err != nil {
+		log.Printf("Erro ao criar convite para o servidor %d: %v", serverID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao criar convite."})
+		return
+	}
+
+	c.JSON(http.StatusCreated, toInviteResponse(invite))
+}
+
+// ListInvites lista os convites ativos do servidor.
+// Quem tem MANAGE_SERVER vê todos; os demais membros veem apenas os que criaram.
+func (ih *InviteHandler) ListInvites(c *gin.Context) {
+	rawUserID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+	userID := rawUserID.(uint)
+
+	serverID, err := strconv.ParseUint(c.Param("serverId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID do servidor inválido"})
+		return
+	}
+
+	pc, err := loadPermissionContext(ih.DB, userID, uint(serverID))
+	if err != nil {
+		respondAuthzError(c, err)
+		return
+	}
+	server := pc.server
+
+	query := ih.DB.Where("server_id = ?", server.ID).Order("created_at DESC")
+	if !pc.has(PermissionManageServer) {
+		query = query.Where("creator_id = ?", userID)
+	}
+	var invites []models.Invite
+	if err := query.Find(&invites).Error; err != nil {
+		log.Printf("Erro ao listar convites do servidor %d: %v", server.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao buscar convites."})
+		return
+	}
+
+	now := time.Now()
+	inviteResponses := []InviteResponse{}
+	for _, invite := range invites {
+		if inviteUsable(&invite, now) {
+			inviteResponses = append(inviteResponses, toInviteResponse(invite))
+		}
+	}
+	c.JSON(http.StatusOK, inviteResponses)
+}
+
+// RevokeInvite revoga um convite. Permitido ao criador do convite e a quem tem MANAGE_SERVER.
+func (ih *InviteHandler) RevokeInvite(c *gin.Context) {
+	rawUserID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+	userID := rawUserID.(uint)
+
+	var invite models.Invite
+	if err := ih.DB.Where("code = ?", c.Param("code")).First(&invite).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Convite não encontrado"})
+			return
+		}
+		log.Printf("Erro ao buscar convite %s: %v", c.Param("code"), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar convite."})
+		return
+	}
+
+	if invite.CreatorID != userID {
+		if _, err := authorizeServerPermission(ih.DB, userID, invite.ServerID, PermissionManageServer); err != nil {
+			respondAuthzError(c, err)
+			return
+		}
+	}
+
+	if err := ih.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&invite).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, auditEntry{
+			ServerID:   invite.ServerID,
+			ActorID:    userID,
+			Action:     AuditInviteDelete,
+			TargetType: AuditTargetInvite,
+			TargetID:   invite.ID,
+			Changes:    auditDiff(inviteAuditFields(invite), nil),
+			Reason:     auditReason(c),
+		})
+	}); err != nil {
+		log.Printf("Erro ao revogar convite %s: %v", invite.Code, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao revogar convite."})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// PreviewInvite é uma rota pública que mostra o servidor de um convite antes de entrar nele.
+func (ih *InviteHandler) PreviewInvite(c *gin.Context) {
+	invite, err := findUsableInvite(ih.DB, c.Param("code"))
+	if err != nil {
+		if errors.Is(err, errInviteUnavailable) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Convite inválido ou expirado."})
+			return
+		}
+		log.Printf("Erro ao buscar convite %s: %v", c.Param("code"), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar convite."})
+		return
+	}
+
+	var server models.Server
+	if err := ih.DB.First(&server, invite.ServerID).Error; err != nil {
+		log.Printf("Erro ao buscar servidor %d do convite %s: %v", invite.ServerID, invite.Code, err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Convite inválido ou expirado."})
+		return
+	}
+
+	memberCount := ih.DB.Model(&server).Association("Members").Count()
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":      invite.Code,
+		"expiresAt": invite.ExpiresAt,
+		"server": gin.H{
+			"id":          server.ID,
+			"name":        server.ServerName,
+			"description": server.Description,
+			"iconUrl":     server.IconURL,
+			"memberCount": memberCount,
+		},
+	})
+}
+
+// JoinInvite adiciona o usuário autenticado ao servidor do convite.
+func (ih *InviteHandler) JoinInvite(c *gin.Context) {
+	rawUserID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+	userID := rawUserID.(uint)
+
+	var server models.Server
+	alreadyMember := false
+	err := ih.DB.Transaction(func(tx *gorm.DB) error {
+		invite, err := findUsableInvite(tx, c.Param("code"))
+		if err != nil {
+			return err
+		}
+		if err := tx.First(&server, invite.ServerID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errInviteUnavailable
+			}
+			return err
+		}
+
+		if alreadyMember, err = isServerMember(tx, userID, server.ID); err != nil || alreadyMember {
+			return err // Já é membro: não consome um uso do convite
+		}
+		if banned, err := isBanned(tx, server.ID, userID); err != nil || banned {
+			if banned {
+				return errBannedFromServer
+			}
+			return err
+		}
+
+		// Incremento condicional para não ultrapassar MaxUses com entradas simultâneas.
+		result := tx.Model(&models.Invite{}).
+			Where("id = ? AND (max_uses = 0 OR uses < max_uses)", invite.ID).
+			UpdateColumn("uses", gorm.Expr("uses + 1"))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errInviteUnavailable
+		}
+
+		return tx.Create(&models.ServerMember{ServerID: server.ID, UserID: userID}).Error
+	})
+	if err != nil {
+		if errors.Is(err, errInviteUnavailable) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Convite inválido ou expirado."})
+			return
+		}
+		if errors.Is(err, errBannedFromServer) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Você foi banido deste servidor."})
+			return
+		}
+		log.Printf("Erro ao entrar no servidor pelo convite %s (usuário %d): %v", c.Param("code"), userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao entrar no servidor."})
+		return
+	}
+
+	status := http.StatusCreated
+	if alreadyMember {
+		status = http.StatusOK
+	} else {
+		dispatchMemberAdd(ih.DB, ih.Hub, server.ID, userID)
+	}
+	c.JSON(status, gin.H{
+		"id":            server.ID,
+		"name":          server.ServerName,
+		"iconUrl":       server.IconURL,
+		"ownerId":       server.OwnerID,
+		"alreadyMember": alreadyMember,
+	})
+}
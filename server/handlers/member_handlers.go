@@ -0,0 +1,553 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gustavoverneck/discordia/server/models"
+	"github.com/gustavoverneck/discordia/server/storage"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultMembersLimit = 100
+	maxMembersLimit     = 1000
+)
+
+var errBannedFromServer = errors.New("você foi banido deste servidor")
+
+type MemberHandler struct {
+	DB      *gorm.DB
+	Hub     *Hub
+	Storage storage.Storage // Onde os avatares dos membros nos servidores são gravados
+}
+
+func NewMemberHandler(db *gorm.DB, hub *Hub, store storage.Storage) *MemberHandler {
+	return &MemberHandler{DB: db, Hub: hub, Storage: store}
+}
+
+// ModerationRequest é o corpo opcional de expulsões e banimentos.
+type ModerationRequest struct {
+	Reason string `json:"reason" binding:"max=512"`
+}
+
+// moderationReason é o motivo do corpo da requisição ou, na falta dele, o do
+// cabeçalho X-Audit-Log-Reason.
+func moderationReason(c *gin.Context, req ModerationRequest) string {
+	if reason := strings.TrimSpace(req.Reason); reason != "" {
+		return reason
+	}
+	return auditReason(c)
+}
+
+type MemberResponse struct {
+	User         UserSummary `json:"user"`
+	Nickname     string      `json:"nickname,omitempty"`
+	AvatarURL    string      `json:"avatarUrl,omitempty"` // Avatar no servidor
+	JoinedAt     time.Time   `json:"joinedAt"`
+	TimeoutUntil *time.Time  `json:"timeoutUntil,omitempty"` // Ausente se o membro não está silenciado
+	Roles        []uint      `json:"roles"`                  // IDs dos cargos do membro, sem o @everyone
+	Status       string      `json:"status"`                 // Presença atual
+}
+
+// MemberPage é a resposta paginada de GET /servers/:serverId/members, em
+// ordem crescente de ID do usuário. NextAfter só é preenchido quando HasMore é verdadeiro.
+type MemberPage struct {
+	Members   []MemberResponse `json:"members"`
+	HasMore   bool             `json:"hasMore"`
+	NextAfter *uint            `json:"nextAfter,omitempty"`
+}
+
+// MemberPayload é o corpo dos eventos member_add e member_update.
+type MemberPayload struct {
+	ServerID uint           `json:"serverId"`
+	Member   MemberResponse `json:"member"`
+}
+
+type MemberRemovePayload struct {
+	ServerID uint        `json:"serverId"`
+	User     UserSummary `json:"user"`
+}
+
+type BanResponse struct {
+	User        UserSummary `json:"user"`
+	ModeratorID uint        `json:"moderatorId"`
+	Reason      string      `json:"reason,omitempty"`
+	CreatedAt   time.Time   `json:"createdAt"`
+}
+
+// loadMembers monta a resposta dos membros informados, com cargos e presença,
+// na ordem de userIDs.
+func loadMembers(db *gorm.DB, hub *Hub, serverID uint, userIDs []uint) ([]MemberResponse, error) {
+	members := make([]MemberResponse, 0, len(userIDs))
+	if len(userIDs) == 0 {
+		return members, nil
+	}
+	var records []models.ServerMember
+	if err := db.Where("server_id = ? AND user_id IN ?", serverID, userIDs).Find(&records).Error; err != nil {
+		return nil, err
+	}
+	var users []models.User
+	if err := db.Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	usersByID := make(map[uint]models.User, len(users))
+	for _, user := range users {
+		usersByID[user.ID] = user
+	}
+	recordsByUser := make(map[uint]models.ServerMember, len(records))
+	for _, record := range records {
+		recordsByUser[record.UserID] = record
+	}
+	var memberRoles []models.MemberRole
+	if err := db.Where("server_id = ? AND user_id IN ?", serverID, userIDs).Order("role_id").Find(&memberRoles).Error; err != nil {
+		return nil, err
+	}
+	rolesByUser := make(map[uint][]uint)
+	for _, mr := range memberRoles {
+		rolesByUser[mr.UserID] = append(rolesByUser[mr.UserID], mr.RoleID)
+	}
+
+	now := time.Now()
+	statuses := hub.Presences(userIDs)
+	for _, userID := range userIDs {
+		user, isUser := usersByID[userID]
+		record, isMember := recordsByUser[userID]
+		if !isUser || !isMember {
+			continue
+		}
+		roles := rolesByUser[userID]
+		if roles == nil {
+			roles = []uint{}
+		}
+		member := MemberResponse{
+			User:      toUserSummary(user),
+			Nickname:  record.Nickname,
+			AvatarURL: record.AvatarURL,
+			JoinedAt:  record.JoinedAt,
+			Roles:     roles,
+			Status:    statuses[userID],
+		}
+		if isTimedOut(record, now) {
+			member.TimeoutUntil = record.TimeoutUntil
+		}
+		members = append(members, member)
+	}
+	return members, nil
+}
+
+// dispatchMemberAdd avisa os membros do servidor (inclusive o novo) sobre a entrada de um membro.
+func dispatchMemberAdd(db *gorm.DB, hub *Hub, serverID, userID uint) {
+	members, err := loadMembers(db, hub, serverID, []uint{userID})
+	if err != nil || len(members) == 0 {
+		log.Printf("Erro ao carregar o membro %d do servidor %d para o evento member_add: %v", userID, serverID, err)
+		return
+	}
+	memberIDs, err := serverMemberIDs(db, serverID)
+	if err != nil {
+		log.Printf("Erro ao buscar membros do servidor %d para o evento member_add: %v", serverID, err)
+		return
+	}
+	hub.DispatchToUsers(memberIDs, "member_add", MemberPayload{ServerID: serverID, Member: members[0]})
+}
+
+// isBanned verifica se o usuário está banido do servidor.
+func isBanned(db *gorm.DB, serverID, userID uint) (bool, error) {
+	var count int64
+	err := db.Model(&models.Ban{}).Where("server_id = ? AND user_id = ?", serverID, userID).Count(&count).Error
+	return count > 0, err
+}
+
+// removeMember apaga a participação do usuário no servidor e os cargos que ele
+// tinha nele, devolvendo o registro de membro removido.
+func removeMember(tx *gorm.DB, serverID, userID uint) (models.ServerMember, error) {
+	var member models.ServerMember
+	if err := tx.Where("server_id = ? AND user_id = ?", serverID, userID).First(&member).Error; err != nil {
+		return member, err
+	}
+	if err := tx.Where("server_id = ? AND user_id = ?", serverID, userID).Delete(&models.MemberRole{}).Error; err != nil {
+		return member, err
+	}
+	return member, tx.Where("server_id = ? AND user_id = ?", serverID, userID).Delete(&models.ServerMember{}).Error
+}
+
+// memberRemoved apaga o avatar do membro no servidor, encerra as inscrições do
+// usuário nos canais do servidor e envia member_remove aos membros restantes e
+// ao próprio usuário.
+func (mh *MemberHandler) memberRemoved(ctx context.Context, removed models.ServerMember, user models.User) {
+	serverID := removed.ServerID
+	deleteImage(ctx, mh.Storage, removed.AvatarURL)
+
+	var channelIDs []uint
+	if err := mh.DB.Model(&models.Channel{}).Where("server_id = ?", serverID).Pluck("id", &channelIDs).Error; err != nil {
+		log.Printf("Erro ao buscar canais do servidor %d: %v", serverID, err)
+	}
+	for _, channelID := range channelIDs {
+		mh.Hub.UnsubscribeUser(user.ID, channelID)
+	}
+
+	memberIDs, err := serverMemberIDs(mh.DB, serverID)
+	if err != nil {
+		log.Printf("Erro ao buscar membros do servidor %d para o evento member_remove: %v", serverID, err)
+		memberIDs = nil
+	}
+	payload := MemberRemovePayload{ServerID: serverID, User: toUserSummary(user)}
+	mh.Hub.DispatchToUsers(append(memberIDs, user.ID), "member_remove", payload)
+}
+
+// ListMembers lida com GET /servers/:serverId/members?limit=&after=<id do usuário>.
+func (mh *MemberHandler) ListMembers(c *gin.Context) {
+	rawUserID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+	userID := rawUserID.(uint)
+
+	serverID, ok := parseIDParam(c, "serverId")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID do servidor inválido"})
+		return
+	}
+
+	limit := defaultMembersLimit
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Paginação inválida: limit deve ser positivo."})
+			return
+		}
+		limit = min(parsed, maxMembersLimit)
+	}
+	after, err := parseIDQuery(c, "after")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Paginação inválida: after deve ser um ID de usuário."})
+		return
+	}
+
+	if _, err := loadPermissionContext(mh.DB, userID, serverID); err != nil {
+		respondAuthzError(c, err)
+		return
+	}
+
+	// Busca um a mais para saber se há outra página
+	var memberIDs []uint
+	if err := mh.DB.Model(&models.ServerMember{}).
+		Where("server_id = ? AND user_id > ?", serverID, after).
+		Order("user_id ASC").Limit(limit+1).
+		Pluck("user_id", &memberIDs).Error; err != nil {
+		log.Printf("Erro ao listar membros do servidor %d: %v", serverID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao buscar membros."})
+		return
+	}
+	page := MemberPage{}
+	if len(memberIDs) > limit {
+		memberIDs = memberIDs[:limit]
+		page.HasMore = true
+		page.NextAfter = &memberIDs[limit-1]
+	}
+
+	if page.Members, err = loadMembers(mh.DB, mh.Hub, serverID, memberIDs); err != nil {
+		log.Printf("Erro ao carregar membros do servidor %d: %v", serverID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao buscar membros."})
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// LeaveServer lida com DELETE /users/me/servers/:serverId. O dono precisa
+// transferir o servidor antes de sair.
+func (mh *MemberHandler) LeaveServer(c *gin.Context) {
+	rawUserID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+	userID := rawUserID.(uint)
+
+	serverID, ok := parseIDParam(c, "serverId")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID do servidor inválido"})
+		return
+	}
+
+	pc, err := loadPermissionContext(mh.DB, userID, serverID)
+	if err != nil {
+		respondAuthzError(c, err)
+		return
+	}
+	if pc.isOwner() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "O dono não pode sair do servidor. Transfira-o ou apague-o."})
+		return
+	}
+
+	var user models.User
+	if err := mh.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Usuário não encontrado"})
+		return
+	}
+	var removed models.ServerMember
+	if err := mh.DB.Transaction(func(tx *gorm.DB) (err error) {
+		removed, err = removeMember(tx, serverID, userID)
+		return err
+	}); err != nil {
+		log.Printf("Erro ao remover usuário %d do servidor %d: %v", userID, serverID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao sair do servidor."})
+		return
+	}
+
+	mh.memberRemoved(c.Request.Context(), removed, user)
+	c.Status(http.StatusNoContent)
+}
+
+// moderation descreve uma expulsão ou um banimento já autorizado.
+type moderation struct {
+	serverID    uint
+	moderatorID uint
+	target      models.User
+	member      bool // O alvo é membro do servidor
+}
+
+// authorizeModeration valida uma expulsão ou banimento: o moderador precisa de
+// required e, se o alvo for membro, de um cargo acima do dele. Em caso de erro
+// a resposta HTTP já foi escrita.
+func (mh *MemberHandler) authorizeModeration(c *gin.Context, required int64) (*moderation, bool) {
+	rawUserID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return nil, false
+	}
+	m := &moderation{moderatorID: rawUserID.(uint)}
+
+	var ok bool
+	if m.serverID, ok = parseIDParam(c, "serverId"); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID do servidor inválido"})
+		return nil, false
+	}
+	targetID, ok := parseIDParam(c, "userId")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID do usuário inválido"})
+		return nil, false
+	}
+
+	pc, err := authorizeServerPermission(mh.DB, m.moderatorID, m.serverID, required)
+	if err != nil {
+		respondAuthzError(c, err)
+		return nil, false
+	}
+	if err := mh.DB.First(&m.target, targetID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Usuário não encontrado"})
+			return nil, false
+		}
+		log.Printf("Erro ao buscar usuário %d: %v", targetID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar usuário."})
+		return nil, false
+	}
+
+	targetPC, err := loadPermissionContext(mh.DB, targetID, m.serverID)
+	if errors.Is(err, errNotServerMember) {
+		return m, true
+	}
+	if err != nil {
+		respondAuthzError(c, err)
+		return nil, false
+	}
+	if !pc.canModerate(targetPC) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Você só pode moderar membros abaixo do seu cargo mais alto."})
+		return nil, false
+	}
+	m.member = true
+	return m, true
+}
+
+// KickMember lida com DELETE /servers/:serverId/members/:userId, com um motivo
+// opcional no corpo. Requer KICK_MEMBERS.
+func (mh *MemberHandler) KickMember(c *gin.Context) {
+	var req ModerationRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) { // Corpo vazio: sem motivo
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos: " + err.Error()})
+		return
+	}
+
+	m, ok := mh.authorizeModeration(c, PermissionKickMembers)
+	if !ok {
+		return
+	}
+	if !m.member {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Membro não encontrado"})
+		return
+	}
+
+	var removed models.ServerMember
+	if err := mh.DB.Transaction(func(tx *gorm.DB) (err error) {
+		if removed, err = removeMember(tx, m.serverID, m.target.ID); err != nil {
+			return err
+		}
+		return recordAudit(tx, auditEntry{
+			ServerID:   m.serverID,
+			ActorID:    m.moderatorID,
+			Action:     AuditMemberKick,
+			TargetType: AuditTargetUser,
+			TargetID:   m.target.ID,
+			Reason:     moderationReason(c, req),
+		})
+	}); err != nil {
+		log.Printf("Erro ao expulsar usuário %d do servidor %d: %v", m.target.ID, m.serverID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao expulsar membro."})
+		return
+	}
+
+	mh.memberRemoved(c.Request.Context(), removed, m.target)
+	c.Status(http.StatusNoContent)
+}
+
+// BanMember lida com PUT /servers/:serverId/bans/:userId. O usuário não precisa
+// ser membro; se for, é removido do servidor. Requer BAN_MEMBERS.
+func (mh *MemberHandler) BanMember(c *gin.Context) {
+	var req ModerationRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) { // Corpo vazio: sem motivo
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos: " + err.Error()})
+		return
+	}
+
+	m, ok := mh.authorizeModeration(c, PermissionBanMembers)
+	if !ok {
+		return
+	}
+
+	var ban models.Ban
+	var removed models.ServerMember
+	err := mh.DB.Transaction(func(tx *gorm.DB) (err error) {
+		// Banir de novo apenas atualiza o motivo e o moderador
+		reason := moderationReason(c, req)
+		if err := tx.Where(models.Ban{ServerID: m.serverID, UserID: m.target.ID}).
+			Assign(map[string]interface{}{"moderator_id": m.moderatorID, "reason": reason}).
+			FirstOrCreate(&ban).Error; err != nil {
+			return err
+		}
+		if m.member {
+			if removed, err = removeMember(tx, m.serverID, m.target.ID); err != nil {
+				return err
+			}
+		}
+		return recordAudit(tx, auditEntry{
+			ServerID:   m.serverID,
+			ActorID:    m.moderatorID,
+			Action:     AuditMemberBanAdd,
+			TargetType: AuditTargetUser,
+			TargetID:   m.target.ID,
+			Reason:     reason,
+		})
+	})
+	if err != nil {
+		log.Printf("Erro ao banir usuário %d do servidor %d: %v", m.target.ID, m.serverID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao banir usuário."})
+		return
+	}
+
+	if m.member {
+		mh.memberRemoved(c.Request.Context(), removed, m.target)
+	}
+	c.JSON(http.StatusOK, BanResponse{User: toUserSummary(m.target), ModeratorID: ban.ModeratorID, Reason: ban.Reason, CreatedAt: ban.CreatedAt})
+}
+
+// ListBans lida com GET /servers/:serverId/bans. Requer BAN_MEMBERS.
+func (mh *MemberHandler) ListBans(c *gin.Context) {
+	rawUserID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+	userID := rawUserID.(uint)
+
+	serverID, ok := parseIDParam(c, "serverId")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID do servidor inválido"})
+		return
+	}
+
+	if _, err := authorizeServerPermission(mh.DB, userID, serverID, PermissionBanMembers); err != nil {
+		respondAuthzError(c, err)
+		return
+	}
+
+	var bans []models.Ban
+	if err := mh.DB.Preload("User").Where("server_id = ?", serverID).Order("created_at DESC").Find(&bans).Error; err != nil {
+		log.Printf("Erro ao listar banimentos do servidor %d: %v", serverID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao buscar banimentos."})
+		return
+	}
+
+	banResponses := []BanResponse{}
+	for _, ban := range bans {
+		banResponses = append(banResponses, BanResponse{
+			User:        toUserSummary(ban.User),
+			ModeratorID: ban.ModeratorID,
+			Reason:      ban.Reason,
+			CreatedAt:   ban.CreatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, banResponses)
+}
+
+// UnbanMember lida com DELETE /servers/:serverId/bans/:userId. Requer BAN_MEMBERS.
+func (mh *MemberHandler) UnbanMember(c *gin.Context) {
+	rawUserID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+	userID := rawUserID.(uint)
+
+	serverID, ok := parseIDParam(c, "serverId")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID do servidor inválido"})
+		return
+	}
+	targetID, ok := parseIDParam(c, "userId")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID do usuário inválido"})
+		return
+	}
+
+	if _, err := authorizeServerPermission(mh.DB, userID, serverID, PermissionBanMembers); err != nil {
+		respondAuthzError(c, err)
+		return
+	}
+
+	err := mh.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("server_id = ? AND user_id = ?", serverID, targetID).Delete(&models.Ban{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return recordAudit(tx, auditEntry{
+			ServerID:   serverID,
+			ActorID:    userID,
+			Action:     AuditMemberBanRemove,
+			TargetType: AuditTargetUser,
+			TargetID:   targetID,
+			Reason:     auditReason(c),
+		})
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Banimento não encontrado"})
+		return
+	}
+	if err != nil {
+		log.Printf("Erro ao remover banimento do usuário %d no servidor %d: %v", targetID, serverID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao remover banimento."})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,309 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gustavoverneck/discordia/server/models"
+	"gorm.io/gorm"
+)
+
+var errMemberTimedOut = errors.New("você está silenciado neste servidor")
+
+// UpdateOwnMemberRequest é o corpo de PATCH /users/me/servers/:serverId. Para
+// trocar o avatar no servidor, use multipart/form-data com os mesmos campos e
+// a imagem em "avatarFile".
+type UpdateOwnMemberRequest struct {
+	Nickname     *string `json:"nickname" form:"nickname" binding:"omitempty,max=32"` // Vazio remove o apelido
+	RemoveAvatar bool    `json:"removeAvatar" form:"removeAvatar"`                    // Volta a exibir o avatar global
+}
+
+// UpdateMemberRequest é o corpo de PATCH /servers/:serverId/members/:userId.
+type UpdateMemberRequest struct {
+	Nickname *string `json:"nickname" binding:"omitempty,max=32"` // Requer MANAGE_NICKNAMES; vazio remove o apelido
+	// TimeoutSeconds silencia o membro pelo tempo informado (até 28 dias); 0
+	// remove o castigo. Requer MODERATE_MEMBERS.
+	TimeoutSeconds *int `json:"timeoutSeconds" binding:"omitempty,min=0,max=2419200"`
+}
+
+// isTimedOut informa se o membro está silenciado no instante now.
+func isTimedOut(member models.ServerMember, now time.Time) bool {
+	return member.TimeoutUntil != nil && member.TimeoutUntil.After(now)
+}
+
+// checkMemberTimeout retorna errMemberTimedOut se o usuário está silenciado no servidor.
+func checkMemberTimeout(db *gorm.DB, serverID, userID uint) error {
+	var member models.ServerMember
+	if err := db.Where("server_id = ? AND user_id = ?", serverID, userID).First(&member).Error; err != nil {
+		return err
+	}
+	if isTimedOut(member, time.Now()) {
+		return errMemberTimedOut
+	}
+	return nil
+}
+
+// checkChannelTimeout aplica checkMemberTimeout ao servidor do canal. DMs não têm castigo.
+func checkChannelTimeout(db *gorm.DB, channel *models.Channel, userID uint) error {
+	if channel.ServerID == nil {
+		return nil
+	}
+	return checkMemberTimeout(db, *channel.ServerID, userID)
+}
+
+// applyMemberProfiles exibe os autores das mensagens como membros do servidor
+// do canal: o apelido substitui o nome de exibição e o avatar no servidor
+// substitui o global. Mensagens de DMs ficam inalteradas.
+func applyMemberProfiles(db *gorm.DB, responses []MessageResponse) error {
+	if len(responses) == 0 {
+		return nil
+	}
+	channelIDs := make([]uint, 0, len(responses))
+	userIDs := make([]uint, 0, len(responses))
+	for _, response := range responses {
+		channelIDs = append(channelIDs, response.ChannelID)
+		userIDs = append(userIDs, response.Author.ID)
+		if response.ParentMessage != nil {
+			userIDs = append(userIDs, response.ParentMessage.Author.ID)
+		}
+	}
+
+	var channels []models.Channel
+	if err := db.Select("id", "server_id").Where("id IN ? AND server_id IS NOT NULL", channelIDs).Find(&channels).Error; err != nil {
+		return err
+	}
+	if len(channels) == 0 {
+		return nil
+	}
+	serverByChannel := make(map[uint]uint, len(channels))
+	serverIDs := make([]uint, 0, len(channels))
+	for _, channel := range channels {
+		serverByChannel[channel.ID] = *channel.ServerID
+		serverIDs = append(serverIDs, *channel.ServerID)
+	}
+
+	var members []models.ServerMember
+	if err := db.Where("server_id IN ? AND user_id IN ? AND (nickname <> '' OR avatar_url <> '')", serverIDs, userIDs).
+		Find(&members).Error; err != nil {
+		return err
+	}
+	type memberKey struct{ serverID, userID uint }
+	profiles := make(map[memberKey]models.ServerMember, len(members))
+	for _, member := range members {
+		profiles[memberKey{member.ServerID, member.UserID}] = member
+	}
+
+	apply := func(serverID uint, author *UserSummary) {
+		member, ok := profiles[memberKey{serverID, author.ID}]
+		if !ok {
+			return
+		}
+		if member.Nickname != "" {
+			author.DisplayName = member.Nickname
+		}
+		if member.AvatarURL != "" {
+			author.AvatarURL = member.AvatarURL
+		}
+	}
+	for i := range responses {
+		serverID, ok := serverByChannel[responses[i].ChannelID]
+		if !ok {
+			continue
+		}
+		apply(serverID, &responses[i].Author)
+		if responses[i].ParentMessage != nil {
+			apply(serverID, &responses[i].ParentMessage.Author)
+		}
+	}
+	return nil
+}
+
+// dispatchMemberUpdate envia o membro atualizado a todos os membros do servidor.
+func (mh *MemberHandler) dispatchMemberUpdate(serverID, userID uint) (*MemberResponse, error) {
+	members, err := loadMembers(mh.DB, mh.Hub, serverID, []uint{userID})
+	if err != nil {
+		return nil, err
+	}
+	if len(members) == 0 {
+		return nil, errNotServerMember
+	}
+	memberIDs, err := serverMemberIDs(mh.DB, serverID)
+	if err != nil {
+		log.Printf("Erro ao buscar membros do servidor %d para o evento member_update: %v", serverID, err)
+	} else {
+		mh.Hub.DispatchToUsers(memberIDs, "member_update", MemberPayload{ServerID: serverID, Member: members[0]})
+	}
+	return &members[0], nil
+}
+
+// UpdateOwnMember lida com PATCH /users/me/servers/:serverId: apelido e avatar
+// do próprio usuário no servidor. O avatar substituído é apagado do armazenamento.
+func (mh *MemberHandler) UpdateOwnMember(c *gin.Context) {
+	rawUserID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+	userID := rawUserID.(uint)
+
+	serverID, ok := parseIDParam(c, "serverId")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID do servidor inválido"})
+		return
+	}
+
+	var member models.ServerMember
+	if err := mh.DB.Where("server_id = ? AND user_id = ?", serverID, userID).First(&member).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondAuthzError(c, errNotServerMember)
+			return
+		}
+		log.Printf("Erro ao buscar membro %d do servidor %d: %v", userID, serverID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar membro."})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImageUploadSize+1024*1024)
+	var req UpdateOwnMemberRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos: " + err.Error()})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.Nickname != nil {
+		updates["nickname"] = strings.TrimSpace(*req.Nickname)
+	}
+
+	newAvatarURL := ""
+	fileHeader, err := c.FormFile("avatarFile")
+	switch {
+	case err == nil:
+		newAvatarURL, err = storeImage(c.Request.Context(), mh.Storage, avatarImage, fileHeader)
+		if err != nil {
+			if message, ok := imageErrorMessage(err); ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": message})
+				return
+			}
+			log.Printf("Erro ao armazenar avatar do usuário %d no servidor %d: %v", userID, serverID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao armazenar o avatar."})
+			return
+		}
+		updates["avatar_url"] = newAvatarURL
+	case errors.Is(err, http.ErrMissingFile), errors.Is(err, http.ErrNotMultipart):
+		if req.RemoveAvatar {
+			updates["avatar_url"] = ""
+		}
+	default:
+		log.Printf("Erro ao processar FormFile 'avatarFile': %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Erro ao processar o arquivo de avatar."})
+		return
+	}
+
+	if len(updates) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Nenhuma alteração informada."})
+		return
+	}
+
+	oldAvatarURL := member.AvatarURL
+	if err := mh.DB.Model(&models.ServerMember{}).
+		Where("server_id = ? AND user_id = ?", serverID, userID).
+		Updates(updates).Error; err != nil {
+		deleteImage(c.Request.Context(), mh.Storage, newAvatarURL)
+		log.Printf("Erro ao atualizar membro %d do servidor %d: %v", userID, serverID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao atualizar o perfil no servidor."})
+		return
+	}
+	if avatarURL, changed := updates["avatar_url"]; changed && oldAvatarURL != "" && oldAvatarURL != avatarURL {
+		deleteImage(c.Request.Context(), mh.Storage, oldAvatarURL)
+	}
+
+	response, err := mh.dispatchMemberUpdate(serverID, userID)
+	if err != nil {
+		log.Printf("Erro ao carregar membro %d do servidor %d: %v", userID, serverID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar membro."})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// UpdateMember lida com PATCH /servers/:serverId/members/:userId: apelido
+// (MANAGE_NICKNAMES) e castigo (MODERATE_MEMBERS) de outro membro, abaixo do
+// cargo mais alto do moderador.
+func (mh *MemberHandler) UpdateMember(c *gin.Context) {
+	var req UpdateMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos: " + err.Error()})
+		return
+	}
+
+	var required int64
+	updates := map[string]interface{}{}
+	if req.Nickname != nil {
+		required |= PermissionManageNicknames
+		updates["nickname"] = strings.TrimSpace(*req.Nickname)
+	}
+	if req.TimeoutSeconds != nil {
+		required |= PermissionModerateMembers
+		if *req.TimeoutSeconds == 0 {
+			updates["timeout_until"] = nil
+		} else {
+			updates["timeout_until"] = time.Now().Add(time.Duration(*req.TimeoutSeconds) * time.Second)
+		}
+	}
+	if len(updates) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Nenhuma alteração informada."})
+		return
+	}
+
+	m, ok := mh.authorizeModeration(c, required)
+	if !ok {
+		return
+	}
+	if !m.member {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Membro não encontrado"})
+		return
+	}
+
+	err := mh.DB.Transaction(func(tx *gorm.DB) error {
+		var member models.ServerMember
+		if err := tx.Where("server_id = ? AND user_id = ?", m.serverID, m.target.ID).First(&member).Error; err != nil {
+			return err
+		}
+		before := memberAuditFields(member)
+		if err := tx.Model(&models.ServerMember{}).
+			Where("server_id = ? AND user_id = ?", m.serverID, m.target.ID).
+			Updates(updates).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("server_id = ? AND user_id = ?", m.serverID, m.target.ID).First(&member).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, auditEntry{
+			ServerID:   m.serverID,
+			ActorID:    m.moderatorID,
+			Action:     AuditMemberUpdate,
+			TargetType: AuditTargetUser,
+			TargetID:   m.target.ID,
+			Changes:    auditDiff(before, memberAuditFields(member)),
+			Reason:     auditReason(c),
+		})
+	})
+	if err != nil {
+		log.Printf("Erro ao atualizar membro %d do servidor %d: %v", m.target.ID, m.serverID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao atualizar membro."})
+		return
+	}
+
+	response, err := mh.dispatchMemberUpdate(m.serverID, m.target.ID)
+	if err != nil {
+		log.Printf("Erro ao carregar membro %d do servidor %d: %v", m.target.ID, m.serverID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar membro."})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,659 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gustavoverneck/discordia/server/models"
+	"gorm.io/gorm"
+)
+
+const (
+	maxMessageLength     = 2000 // Tamanho máximo do conteúdo de uma mensagem, em caracteres
+	messagePreviewLength = 100  // Tamanho da citação da mensagem respondida
+	defaultRepliesLimit  = 50
+	maxRepliesLimit      = 100
+)
+
+var (
+	errMessageNotFound  = errors.New("mensagem não encontrada")
+	errNotMessageAuthor = errors.New("apenas o autor pode editar a mensagem")
+	errParentNotFound   = errors.New("a mensagem respondida não existe neste canal")
+	errInvalidContent   = errors.New("a mensagem precisa ter conteúdo (até 2000 caracteres) ou anexos")
+	errNotTextChannel   = errors.New("este canal não suporta mensagens de texto")
+)
+
+// supportsText informa se o tipo de canal tem mensagens de texto: canais de voz
+// e categorias não têm.
+func supportsText(channelType string) bool {
+	return channelType != ChannelTypeVoice && channelType != ChannelTypeCategory
+}
+
+type EditMessageRequest struct {
+	Content string `json:"content" binding:"required,min=1,max=2000"`
+}
+
+// MessageDeletePayload é o corpo do evento message_delete.
+type MessageDeletePayload struct {
+	ID        uint `json:"id"`
+	ChannelID uint `json:"channelId"`
+}
+
+// ReplyPage é a resposta paginada de GET /channels/:channelId/messages/:messageId/replies,
+// das respostas mais antigas para as mais novas. NextAfter só é preenchido
+// quando HasMore é verdadeiro.
+type ReplyPage struct {
+	Replies   []MessageResponse `json:"replies"`
+	HasMore   bool              `json:"hasMore"`
+	NextAfter *uint             `json:"nextAfter,omitempty"`
+}
+
+type MessageEditResponse struct {
+	Content  string    `json:"content"`  // Conteúdo anterior à edição
+	EditedAt time.Time `json:"editedAt"` // Momento em que foi substituído
+}
+
+// mentionPattern reconhece menções a usuários no formato <@id>.
+var mentionPattern = regexp.MustCompile(`<@(\d+)>`)
+
+// parseMentionIDs extrai, sem repetições, os IDs mencionados no conteúdo.
+func parseMentionIDs(content string) []uint {
+	seen := make(map[uint]bool)
+	var ids []uint
+	for _, match := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		id, err := strconv.ParseUint(match[1], 10, 32)
+		if err != nil || id == 0 || seen[uint(id)] {
+			continue
+		}
+		seen[uint(id)] = true
+		ids = append(ids, uint(id))
+	}
+	return ids
+}
+
+// syncMessageMentions substitui as menções registradas da mensagem pelas
+// presentes no seu conteúdo. Apenas quem pode estar no canal é registrado:
+// membros do servidor ou, em DMs, os participantes da conversa.
+// Os contadores de menções não lidas acompanham as menções adicionadas e removidas.
+func syncMessageMentions(tx *gorm.DB, message *models.Message, channel *models.Channel) error {
+	var previousIDs []uint
+	if err := tx.Model(&models.MessageMention{}).Where("message_id = ?", message.ID).Pluck("user_id", &previousIDs).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("message_id = ?", message.ID).Delete(&models.MessageMention{}).Error; err != nil {
+		return err
+	}
+
+	var memberIDs []uint
+	if ids := parseMentionIDs(message.Content); len(ids) > 0 {
+		members := tx.Table("server_members").Where("server_id = ? AND user_id IN ?", channel.ServerID, ids)
+		if channel.ServerID == nil {
+			members = tx.Table("channel_recipients").Where("channel_id = ? AND user_id IN ?", channel.ID, ids)
+		}
+		if err := members.Pluck("user_id", &memberIDs).Error; err != nil {
+			return err
+		}
+	}
+
+	added, removed := diffMentionIDs(previousIDs, memberIDs, message.AuthorID)
+	if err := adjustMentionCounts(tx, message, added, 1); err != nil {
+		return err
+	}
+	if err := adjustMentionCounts(tx, message, removed, -1); err != nil {
+		return err
+	}
+
+	if len(memberIDs) == 0 {
+		return nil
+	}
+	mentions := make([]models.MessageMention, 0, len(memberIDs))
+	for _, id := range memberIDs {
+		mentions = append(mentions, models.MessageMention{MessageID: message.ID, UserID: id})
+	}
+	return tx.Create(&mentions).Error
+}
+
+// diffMentionIDs compara as menções antes e depois de uma edição. O autor
+// nunca conta como mencionado para fins de mensagens não lidas.
+func diffMentionIDs(previous, current []uint, authorID uint) (added, removed []uint) {
+	before := make(map[uint]bool, len(previous))
+	for _, id := range previous {
+		before[id] = true
+	}
+	after := make(map[uint]bool, len(current))
+	for _, id := range current {
+		after[id] = true
+		if !before[id] && id != authorID {
+			added = append(added, id)
+		}
+	}
+	for _, id := range previous {
+		if !after[id] && id != authorID {
+			removed = append(removed, id)
+		}
+	}
+	return added, removed
+}
+
+// validMessageContent informa se o conteúdo pode ser salvo como mensagem.
+func validMessageContent(content string) bool {
+	return strings.TrimSpace(content) != "" && len([]rune(content)) <= maxMessageLength
+}
+
+// truncateRunes corta s em no máximo n caracteres, indicando o corte com reticências.
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "…"
+}
+
+// withMessageRelations carrega o autor, os anexos e a mensagem respondida (com
+// seu autor) necessários para montar um MessageResponse.
+func withMessageRelations(db *gorm.DB) *gorm.DB {
+	return db.Preload("Author").Preload("Attachments").Preload("ParentMessage.Author")
+}
+
+// loadReplyCounts conta as respostas (não apagadas) de cada mensagem.
+func loadReplyCounts(db *gorm.DB, messageIDs []uint) (map[uint]int64, error) {
+	counts := make(map[uint]int64, len(messageIDs))
+	if len(messageIDs) == 0 {
+		return counts, nil
+	}
+	var rows []struct {
+		ParentMessageID uint
+		Count           int64
+	}
+	err := db.Model(&models.Message{}).
+		Select("parent_message_id, COUNT(*) AS count").
+		Where("parent_message_id IN ?", messageIDs).
+		Group("parent_message_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		counts[row.ParentMessageID] = row.Count
+	}
+	return counts, nil
+}
+
+// buildMessageResponses converte mensagens carregadas com withMessageRelations
+// em DTOs, preenchendo a contagem de respostas de cada uma e os autores como
+// membros do servidor.
+func buildMessageResponses(db *gorm.DB, messages []models.Message) ([]MessageResponse, error) {
+	messageIDs := make([]uint, len(messages))
+	for i, msg := range messages {
+		messageIDs[i] = msg.ID
+	}
+	replyCounts, err := loadReplyCounts(db, messageIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	messageResponses := make([]MessageResponse, 0, len(messages))
+	for _, msg := range messages {
+		response := toMessageResponse(msg)
+		response.ReplyCount = replyCounts[msg.ID]
+		messageResponses = append(messageResponses, response)
+	}
+	if err := applyMemberProfiles(db, messageResponses); err != nil {
+		return nil, err
+	}
+	return messageResponses, nil
+}
+
+// buildMessageResponse é a versão de buildMessageResponses para uma única mensagem.
+func buildMessageResponse(db *gorm.DB, message models.Message) (MessageResponse, error) {
+	responses, err := buildMessageResponses(db, []models.Message{message})
+	if err != nil {
+		return MessageResponse{}, err
+	}
+	return responses[0], nil
+}
+
+// findChannelMessage busca uma mensagem garantindo que ela pertence ao canal.
+func findChannelMessage(db *gorm.DB, channelID, messageID uint) (*models.Message, error) {
+	var message models.Message
+	if err := db.Scopes(withMessageRelations).Where("id = ? AND channel_id = ?", messageID, channelID).First(&message).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errMessageNotFound
+		}
+		return nil, err
+	}
+	return &message, nil
+}
+
+// authorizeMessageSend garante que o usuário pode enviar mensagens no canal:
+// VIEW_CHANNEL e SEND_MESSAGES, canal de texto e sem castigo ativo.
+func authorizeMessageSend(db *gorm.DB, userID, channelID uint) (*models.Channel, error) {
+	channel, _, err := authorizeChannel(db, userID, channelID, PermissionViewChannel|PermissionSendMessages)
+	if err != nil {
+		return nil, err
+	}
+	if !supportsText(channel.ChannelType) {
+		return nil, errNotTextChannel
+	}
+	// Membros silenciados não enviam mensagens até o fim do castigo
+	if err := checkChannelTimeout(db, channel, userID); err != nil {
+		return nil, err
+	}
+	return channel, nil
+}
+
+// createMessage salva uma nova mensagem com seus anexos (já gravados em disco),
+// transmite message_create aos inscritos no canal e marca o canal como lido
+// para o autor. Sem anexos, o conteúdo não pode ser vazio.
+func createMessage(db *gorm.DB, hub *Hub, authorID uint, payload NewMessagePayload, attachments []models.Attachment) (*MessageResponse, error) {
+	// O autor precisa poder enviar mensagens no canal
+	channel, err := authorizeMessageSend(db, authorID, payload.ChannelID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Uma resposta só pode citar uma mensagem do mesmo canal
+	if payload.ParentMessageID != nil {
+		if _, err := findChannelMessage(db, payload.ChannelID, *payload.ParentMessageID); err != nil {
+			if errors.Is(err, errMessageNotFound) {
+				err = errParentNotFound
+			}
+			return nil, err
+		}
+	}
+
+	if len([]rune(payload.Content)) > maxMessageLength || (strings.TrimSpace(payload.Content) == "" && len(attachments) == 0) {
+		return nil, errInvalidContent
+	}
+
+	message := models.Message{
+		ChannelID:       payload.ChannelID,
+		AuthorID:        authorID,
+		Content:         payload.Content,
+		ParentMessageID: payload.ParentMessageID,
+		Attachments:     attachments,
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&message).Error; err != nil {
+			return err
+		}
+		return syncMessageMentions(tx, &message, channel)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Recarrega o autor (e a mensagem respondida) para enviar na resposta
+	if err := db.Scopes(withMessageRelations).First(&message, message.ID).Error; err != nil {
+		log.Printf("Erro ao carregar autor %d da mensagem %d: %v", authorID, message.ID, err)
+	}
+	responses := []MessageResponse{toMessageResponse(message)}
+	if err := applyMemberProfiles(db, responses); err != nil {
+		log.Printf("Erro ao carregar perfil de membro do autor %d: %v", authorID, err)
+	}
+	response := responses[0]
+
+	// O hub numera o evento por sessão e o entrega na fila de cada conexão.
+	hub.BroadcastToChannel(payload.ChannelID, "message_create", response)
+	// A mensagem chegou: o autor não está mais digitando
+	hub.StopTyping(authorID, payload.ChannelID)
+	// Quem envia uma mensagem já leu o canal até ela
+	if _, err := acknowledge(db, hub, authorID, payload.ChannelID, message.ID); err != nil {
+		log.Printf("Erro ao marcar canal %d como lido para o usuário %d: %v", payload.ChannelID, authorID, err)
+	}
+	return &response, nil
+}
+
+// editMessage substitui o conteúdo de uma mensagem, guardando o conteúdo
+// anterior no histórico. Apenas o autor pode editar, e só enquanto puder enviar
+// mensagens no canal.
+func editMessage(db *gorm.DB, userID, channelID, messageID uint, content string) (*models.Message, error) {
+	channel, _, err := authorizeChannel(db, userID, channelID, PermissionViewChannel|PermissionSendMessages)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkChannelTimeout(db, channel, userID); err != nil {
+		return nil, err
+	}
+	message, err := findChannelMessage(db, channelID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if message.AuthorID != userID {
+		return nil, errNotMessageAuthor
+	}
+	if message.Content == content {
+		return message, nil // Nada mudou: não cria entrada no histórico
+	}
+
+	now := time.Now()
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&models.MessageEdit{MessageID: message.ID, Content: message.Content}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(message).Updates(map[string]interface{}{"content": content, "edited_at": now}).Error; err != nil {
+			return err
+		}
+		message.Content = content
+		return syncMessageMentions(tx, message, channel)
+	})
+	if err != nil {
+		return nil, err
+	}
+	message.EditedAt = &now
+	return message, nil
+}
+
+// deleteMessage remove (soft delete) uma mensagem. Permitido ao autor e a
+// quem tem MANAGE_MESSAGES no canal; a remoção da mensagem de outro usuário em
+// um servidor vai para o registro de auditoria com o motivo informado.
+func deleteMessage(db *gorm.DB, userID, channelID, messageID uint, reason string) error {
+	channel, perms, err := authorizeChannel(db, userID, channelID, PermissionViewChannel)
+	if err != nil {
+		return err
+	}
+	message, err := findChannelMessage(db, channelID, messageID)
+	if err != nil {
+		return err
+	}
+	if message.AuthorID != userID && perms&PermissionManageMessages == 0 {
+		return errMissingPermission
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		var mentionedIDs []uint
+		if err := tx.Model(&models.MessageMention{}).Where("message_id = ? AND user_id <> ?", message.ID, message.AuthorID).Pluck("user_id", &mentionedIDs).Error; err != nil {
+			return err
+		}
+		if err := adjustMentionCounts(tx, message, mentionedIDs, -1); err != nil {
+			return err
+		}
+		if err := tx.Delete(message).Error; err != nil {
+			return err
+		}
+		if message.AuthorID == userID || channel.ServerID == nil {
+			return nil
+		}
+		return recordAudit(tx, auditEntry{
+			ServerID:   *channel.ServerID,
+			ActorID:    userID,
+			Action:     AuditMessageDelete,
+			TargetType: AuditTargetMessage,
+			TargetID:   message.ID,
+			Options:    map[string]interface{}{"channel_id": channelID, "author_id": message.AuthorID},
+			Reason:     reason,
+		})
+	})
+}
+
+// CreateMessageRequest é o corpo de POST /channels/:channelId/messages. Para
+// enviar anexos, use multipart/form-data com os mesmos campos e os arquivos em "files".
+type CreateMessageRequest struct {
+	Content         string `json:"content" form:"content"`
+	ParentMessageID *uint  `json:"parentMessageId" form:"parentMessageId"`
+}
+
+// CreateMessage lida com POST /channels/:channelId/messages, o envio de
+// mensagens pela API REST, com anexos opcionais.
+func (ch *ChannelHandler) CreateMessage(c *gin.Context) {
+	rawUserID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+	userID := rawUserID.(uint)
+
+	channelID, ok := parseIDParam(c, "channelId")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID do canal inválido"})
+		return
+	}
+
+	// Verifica o canal antes de ler o corpo e gravar anexos que seriam descartados;
+	// createMessage repete a verificação ao salvar a mensagem.
+	if _, err := authorizeMessageSend(ch.DB, userID, channelID); err != nil {
+		respondAuthzError(c, err)
+		return
+	}
+
+	// Folga para os demais campos do formulário além dos anexos
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxAttachmentsTotalSize+1024*1024)
+	var req CreateMessageRequest
+	if err := c.ShouldBind(&req); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			respondAuthzError(c, errAttachmentTooLarge)
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos: " + err.Error()})
+		return
+	}
+
+	var attachments []models.Attachment
+	if form := c.Request.MultipartForm; form != nil && len(form.File["files"]) > 0 {
+		var err error
+		attachments, err = saveAttachments(c.Request.Context(), ch.Storage, form.File["files"])
+		if err != nil {
+			if authzErrorStatus(err) == http.StatusInternalServerError {
+				log.Printf("Erro ao salvar anexos no canal %d: %v", channelID, err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao salvar anexos."})
+				return
+			}
+			respondAuthzError(c, err)
+			return
+		}
+	}
+
+	payload := NewMessagePayload{ChannelID: channelID, Content: req.Content, ParentMessageID: req.ParentMessageID}
+	response, err := createMessage(ch.DB, ch.Hub, userID, payload, attachments)
+	if err != nil {
+		removeAttachmentFiles(c.Request.Context(), ch.Storage, attachments)
+		respondAuthzError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, response)
+}
+
+// EditMessage lida com PATCH /channels/:channelId/messages/:messageId.
+func (ch *ChannelHandler) EditMessage(c *gin.Context) {
+	rawUserID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+	userID := rawUserID.(uint)
+
+	channelID, ok := parseIDParam(c, "channelId")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID do canal inválido"})
+		return
+	}
+	messageID, ok := parseIDParam(c, "messageId")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID da mensagem inválido"})
+		return
+	}
+
+	var req EditMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos: " + err.Error()})
+		return
+	}
+	if !validMessageContent(req.Content) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "O conteúdo da mensagem não pode ser vazio."})
+		return
+	}
+
+	message, err := editMessage(ch.DB, userID, channelID, messageID, req.Content)
+	if err != nil {
+		respondAuthzError(c, err)
+		return
+	}
+
+	response, err := buildMessageResponse(ch.DB, *message)
+	if err != nil {
+		log.Printf("Erro ao montar resposta da mensagem %d: %v", message.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao buscar mensagem."})
+		return
+	}
+	ch.Hub.BroadcastToChannel(channelID, "message_update", response)
+	c.JSON(http.StatusOK, response)
+}
+
+// DeleteMessage lida com DELETE /channels/:channelId/messages/:messageId.
+func (ch *ChannelHandler) DeleteMessage(c *gin.Context) {
+	rawUserID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+	userID := rawUserID.(uint)
+
+	channelID, ok := parseIDParam(c, "channelId")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID do canal inválido"})
+		return
+	}
+	messageID, ok := parseIDParam(c, "messageId")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID da mensagem inválido"})
+		return
+	}
+
+	if err := deleteMessage(ch.DB, userID, channelID, messageID, auditReason(c)); err != nil {
+		respondAuthzError(c, err)
+		return
+	}
+
+	ch.Hub.BroadcastToChannel(channelID, "message_delete", MessageDeletePayload{ID: messageID, ChannelID: channelID})
+	c.Status(http.StatusNoContent)
+}
+
+// ListMessageEdits lista as versões anteriores de uma mensagem, da mais recente para a mais antiga.
+func (ch *ChannelHandler) ListMessageEdits(c *gin.Context) {
+	rawUserID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+	userID := rawUserID.(uint)
+
+	channelID, ok := parseIDParam(c, "channelId")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID do canal inválido"})
+		return
+	}
+	messageID, ok := parseIDParam(c, "messageId")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID da mensagem inválido"})
+		return
+	}
+
+	if _, _, err := authorizeChannel(ch.DB, userID, channelID, PermissionViewChannel|PermissionReadMessageHistory); err != nil {
+		respondAuthzError(c, err)
+		return
+	}
+	if _, err := findChannelMessage(ch.DB, channelID, messageID); err != nil {
+		respondAuthzError(c, err)
+		return
+	}
+
+	var edits []models.MessageEdit
+	if err := ch.DB.Where("message_id = ?", messageID).Order("id DESC").Find(&edits).Error; err != nil {
+		log.Printf("Erro ao buscar histórico da mensagem %d: %v", messageID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao buscar histórico da mensagem."})
+		return
+	}
+
+	editResponses := []MessageEditResponse{}
+	for _, edit := range edits {
+		editResponses = append(editResponses, MessageEditResponse{Content: edit.Content, EditedAt: edit.CreatedAt})
+	}
+	c.JSON(http.StatusOK, editResponses)
+}
+
+// ListReplies lista as respostas diretas de uma mensagem, das mais antigas para
+// as mais novas. Use ?after=<nextAfter da página anterior> para paginar.
+func (ch *ChannelHandler) ListReplies(c *gin.Context) {
+	rawUserID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+	userID := rawUserID.(uint)
+
+	channelID, ok := parseIDParam(c, "channelId")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID do canal inválido"})
+		return
+	}
+	messageID, ok := parseIDParam(c, "messageId")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID da mensagem inválido"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultRepliesLimit)))
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Parâmetro limit inválido"})
+		return
+	}
+	if limit > maxRepliesLimit {
+		limit = maxRepliesLimit
+	}
+	var afterID uint64
+	if after := c.Query("after"); after != "" {
+		if afterID, err = strconv.ParseUint(after, 10, 32); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parâmetro after inválido"})
+			return
+		}
+	}
+
+	if _, _, err := authorizeChannel(ch.DB, userID, channelID, PermissionViewChannel|PermissionReadMessageHistory); err != nil {
+		respondAuthzError(c, err)
+		return
+	}
+	// A mensagem original pode ter sido apagada; suas respostas continuam acessíveis.
+	var parentCount int64
+	if err := ch.DB.Unscoped().Model(&models.Message{}).
+		Where("id = ? AND channel_id = ?", messageID, channelID).Count(&parentCount).Error; err != nil {
+		log.Printf("Erro ao buscar mensagem %d: %v", messageID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao buscar respostas."})
+		return
+	}
+	if parentCount == 0 {
+		respondAuthzError(c, errMessageNotFound)
+		return
+	}
+
+	var replies []models.Message
+	err = ch.DB.Scopes(withMessageRelations).
+		Where("parent_message_id = ? AND id > ?", messageID, afterID).
+		Order("id ASC").
+		Limit(limit + 1). // Um registro extra indica se há mais respostas
+		Find(&replies).Error
+	if err != nil {
+		log.Printf("Erro ao buscar respostas da mensagem %d: %v", messageID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao buscar respostas."})
+		return
+	}
+
+	page := ReplyPage{HasMore: len(replies) > limit}
+	if page.HasMore {
+		replies = replies[:limit]
+		nextAfter := replies[len(replies)-1].ID
+		page.NextAfter = &nextAfter
+	}
+	page.Replies, err = buildMessageResponses(ch.DB, replies)
+	if err != nil {
+		log.Printf("Erro ao contar respostas da mensagem %d: %v", messageID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao buscar respostas."})
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gustavoverneck/discordia/server/models"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultMessagesLimit = 50
+	maxMessagesLimit     = 100
+)
+
+var errInvalidPagination = errors.New("parâmetros de paginação inválidos")
+
+// messageCursor descreve qual página do histórico buscar. No máximo um entre
+// Before, After e Around é diferente de zero; sem cursor, a página é a mais recente.
+type messageCursor struct {
+	Limit  int
+	Before uint // Mensagens com ID menor que Before
+	After  uint // Mensagens com ID maior que After
+	Around uint // Mensagens ao redor de Around (incluindo ela, se existir)
+}
+
+// MessagePage é a resposta paginada do histórico de um canal.
+// Messages está sempre em ordem decrescente de ID (mais recentes primeiro).
+// NextBefore e NextAfter são os cursores para continuar a rolagem em cada direção
+// e só são preenchidos quando HasMoreBefore/HasMoreAfter são verdadeiros.
+type MessagePage struct {
+	Messages      []MessageResponse `json:"messages"`
+	HasMoreBefore bool              `json:"hasMoreBefore"`
+	HasMoreAfter  bool              `json:"hasMoreAfter"`
+	NextBefore    *uint             `json:"nextBefore,omitempty"`
+	NextAfter     *uint             `json:"nextAfter,omitempty"`
+}
+
+// parseIDQuery lê um parâmetro de query opcional com um ID; ausente vale 0.
+func parseIDQuery(c *gin.Context, name string) (uint, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseUint(raw, 10, 32)
+	if err != nil || id == 0 {
+		return 0, errInvalidPagination
+	}
+	return uint(id), nil
+}
+
+// parseMessageCursor valida limit, before, after e around.
+func parseMessageCursor(c *gin.Context) (messageCursor, error) {
+	cursor := messageCursor{Limit: defaultMessagesLimit}
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 {
+			return cursor, errInvalidPagination
+		}
+		if limit > maxMessagesLimit { // Limite máximo
+			limit = maxMessagesLimit
+		}
+		cursor.Limit = limit
+	}
+
+	var err error
+	if cursor.Before, err = parseIDQuery(c, "before"); err != nil {
+		return cursor, err
+	}
+	if cursor.After, err = parseIDQuery(c, "after"); err != nil {
+		return cursor, err
+	}
+	if cursor.Around, err = parseIDQuery(c, "around"); err != nil {
+		return cursor, err
+	}
+
+	set := 0
+	for _, id := range []uint{cursor.Before, cursor.After, cursor.Around} {
+		if id != 0 {
+			set++
+		}
+	}
+	if set > 1 {
+		return cursor, errInvalidPagination
+	}
+	return cursor, nil
+}
+
+// fetchOlder busca até limit mensagens com ID < before (ou <= quando inclusive),
+// em ordem decrescente, e informa se existem mais além delas.
+func fetchOlder(query *gorm.DB, before uint, inclusive bool, limit int) ([]models.Message, bool, error) {
+	var messages []models.Message
+	q := query.Session(&gorm.Session{}).Scopes(withMessageRelations)
+	if before != 0 {
+		if inclusive {
+			q = q.Where("id <= ?", before)
+		} else {
+			q = q.Where("id < ?", before)
+		}
+	}
+	if err := q.Order("id DESC").Limit(limit + 1).Find(&messages).Error; err != nil {
+		return nil, false, err
+	}
+	if len(messages) > limit {
+		return messages[:limit], true, nil
+	}
+	return messages, false, nil
+}
+
+// fetchNewer busca até limit mensagens com ID > after, em ordem crescente,
+// e informa se existem mais além delas.
+func fetchNewer(query *gorm.DB, after uint, limit int) ([]models.Message, bool, error) {
+	var messages []models.Message
+	err := query.Session(&gorm.Session{}).Scopes(withMessageRelations).
+		Where("id > ?", after).
+		Order("id ASC").
+		Limit(limit + 1).
+		Find(&messages).Error
+	if err != nil {
+		return nil, false, err
+	}
+	if len(messages) > limit {
+		return messages[:limit], true, nil
+	}
+	return messages, false, nil
+}
+
+// existsMessage informa se alguma mensagem da query satisfaz a condição.
+func existsMessage(query *gorm.DB, condition string, id uint) (bool, error) {
+	var count int64
+	err := query.Session(&gorm.Session{}).Model(&models.Message{}).
+		Where(condition, id).Limit(1).Count(&count).Error
+	return count > 0, err
+}
+
+// fetchMessagePage busca uma página do histórico de acordo com o cursor.
+// query deve conter apenas os filtros (ex: canal); as relações de
+// withMessageRelations são carregadas aqui. A ordenação é por ID, que é estável
+// mesmo quando várias mensagens têm o mesmo CreatedAt.
+// O resultado está em ordem decrescente de ID.
+func fetchMessagePage(query *gorm.DB, cursor messageCursor) ([]models.Message, bool, bool, error) {
+	var (
+		messages                    []models.Message
+		hasMoreBefore, hasMoreAfter bool
+		err                         error
+	)
+
+	switch {
+	case cursor.After != 0:
+		messages, hasMoreAfter, err = fetchNewer(query, cursor.After, cursor.Limit)
+		if err != nil {
+			return nil, false, false, err
+		}
+		// Inverte para manter a ordem decrescente
+		for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+			messages[i], messages[j] = messages[j], messages[i]
+		}
+		if hasMoreBefore, err = existsMessage(query, "id <= ?", cursor.After); err != nil {
+			return nil, false, false, err
+		}
+
+	case cursor.Around != 0:
+		// Metade (arredondada para cima) fica com a mensagem alvo e as anteriores.
+		newerLimit := cursor.Limit / 2
+		var older, newer []models.Message
+		older, hasMoreBefore, err = fetchOlder(query, cursor.Around, true, cursor.Limit-newerLimit)
+		if err != nil {
+			return nil, false, false, err
+		}
+		if newerLimit > 0 {
+			if newer, hasMoreAfter, err = fetchNewer(query, cursor.Around, newerLimit); err != nil {
+				return nil, false, false, err
+			}
+		} else if hasMoreAfter, err = existsMessage(query, "id > ?", cursor.Around); err != nil {
+			return nil, false, false, err
+		}
+		messages = make([]models.Message, 0, len(newer)+len(older))
+		for i := len(newer) - 1; i >= 0; i-- {
+			messages = append(messages, newer[i])
+		}
+		messages = append(messages, older...)
+
+	default:
+		// Sem cursor (página mais recente) ou com before
+		messages, hasMoreBefore, err = fetchOlder(query, cursor.Before, false, cursor.Limit)
+		if err != nil {
+			return nil, false, false, err
+		}
+		if cursor.Before != 0 {
+			if hasMoreAfter, err = existsMessage(query, "id >= ?", cursor.Before); err != nil {
+				return nil, false, false, err
+			}
+		}
+	}
+
+	return messages, hasMoreBefore, hasMoreAfter, nil
+}
+
+// newMessagePage monta a resposta paginada com os cursores das duas direções.
+func newMessagePage(messages []MessageResponse, hasMoreBefore, hasMoreAfter bool) MessagePage {
+	page := MessagePage{Messages: messages, HasMoreBefore: hasMoreBefore, HasMoreAfter: hasMoreAfter}
+	if len(messages) > 0 {
+		if hasMoreBefore {
+			oldest := messages[len(messages)-1].ID
+			page.NextBefore = &oldest
+		}
+		if hasMoreAfter {
+			newest := messages[0].ID
+			page.NextAfter = &newest
+		}
+	}
+	return page
+}
@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gustavoverneck/discordia/server/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestDB abre um banco SQLite em memória com as tabelas informadas.
+func newTestDB(t *testing.T, tables ...interface{}) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("falha ao abrir o banco de teste: %v", err)
+	}
+	// Cada conexão a ":memory:" abriria um banco vazio diferente
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("falha ao abrir o banco de teste: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(tables...); err != nil {
+		t.Fatalf("falha ao migrar o banco de teste: %v", err)
+	}
+	return db
+}
+
+func newQueryContext(rawQuery string) *gin.Context {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/?"+rawQuery, nil)
+	return c
+}
+
+func TestParseMessageCursor(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    messageCursor
+		wantErr bool
+	}{
+		{"", messageCursor{Limit: defaultMessagesLimit}, false},
+		{"limit=10", messageCursor{Limit: 10}, false},
+		{"limit=1000", messageCursor{Limit: maxMessagesLimit}, false},
+		{"limit=0", messageCursor{}, true},
+		{"limit=-5", messageCursor{}, true},
+		{"limit=abc", messageCursor{}, true},
+		{"before=42", messageCursor{Limit: defaultMessagesLimit, Before: 42}, false},
+		{"after=42&limit=5", messageCursor{Limit: 5, After: 42}, false},
+		{"around=42", messageCursor{Limit: defaultMessagesLimit, Around: 42}, false},
+		{"before=0", messageCursor{}, true},
+		{"before=-1", messageCursor{}, true},
+		{"after=x", messageCursor{}, true},
+		{"around=99999999999", messageCursor{}, true},
+		{"before=1&after=2", messageCursor{}, true},
+		{"after=1&around=2", messageCursor{}, true},
+		{"before=1&around=2", messageCursor{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got, err := parseMessageCursor(newQueryContext(tt.query))
+			if tt.wantErr {
+				if !errors.Is(err, errInvalidPagination) {
+					t.Fatalf("erro = %v, esperado errInvalidPagination", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("erro inesperado: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("cursor = %+v, esperado %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func responsesWithIDs(ids ...uint) []MessageResponse {
+	responses := make([]MessageResponse, len(ids))
+	for i, id := range ids {
+		responses[i].ID = id
+	}
+	return responses
+}
+
+func TestNewMessagePage(t *testing.T) {
+	tests := []struct {
+		name                        string
+		messages                    []MessageResponse
+		hasMoreBefore, hasMoreAfter bool
+		wantBefore, wantAfter       uint // 0 = cursor ausente
+	}{
+		{"sem mais páginas", responsesWithIDs(5, 4, 3), false, false, 0, 0},
+		{"mais antigas", responsesWithIDs(5, 4, 3), true, false, 3, 0},
+		{"mais novas", responsesWithIDs(5, 4, 3), false, true, 0, 5},
+		{"ambas as direções", responsesWithIDs(5, 4, 3), true, true, 3, 5},
+		{"página vazia não tem cursores", responsesWithIDs(), true, true, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := newMessagePage(tt.messages, tt.hasMoreBefore, tt.hasMoreAfter)
+			if page.HasMoreBefore != tt.hasMoreBefore || page.HasMoreAfter != tt.hasMoreAfter {
+				t.Errorf("hasMore = %v/%v, esperado %v/%v", page.HasMoreBefore, page.HasMoreAfter, tt.hasMoreBefore, tt.hasMoreAfter)
+			}
+			if got := cursorValue(page.NextBefore); got != tt.wantBefore {
+				t.Errorf("nextBefore = %d, esperado %d", got, tt.wantBefore)
+			}
+			if got := cursorValue(page.NextAfter); got != tt.wantAfter {
+				t.Errorf("nextAfter = %d, esperado %d", got, tt.wantAfter)
+			}
+		})
+	}
+}
+
+func TestMessagePageJSON(t *testing.T) {
+	// O histórico é um objeto, não um array; os cursores ausentes são omitidos.
+	tests := []struct {
+		name string
+		page MessagePage
+		want string
+	}{
+		{"sem cursores", newMessagePage(responsesWithIDs(), false, false),
+			`{"messages":[],"hasMoreBefore":false,"hasMoreAfter":false}`},
+		{"com cursores", newMessagePage(responsesWithIDs(9, 7), true, true),
+			`{"hasMoreBefore":true,"hasMoreAfter":true,"nextBefore":7,"nextAfter":9}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.page)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got map[string]interface{}
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatal(err)
+			}
+			var want map[string]interface{}
+			if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := got["messages"].([]interface{}); !ok {
+				t.Errorf("messages deveria ser um array: %s", data)
+			}
+			delete(got, "messages")
+			delete(want, "messages")
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("JSON = %s, esperado %s", data, tt.want)
+			}
+		})
+	}
+}
+
+func cursorValue(cursor *uint) uint {
+	if cursor == nil {
+		return 0
+	}
+	return *cursor
+}
+
+func TestFetchMessagePage(t *testing.T) {
+	db := newTestDB(t, &models.User{}, &models.Message{}, &models.Attachment{})
+	author := models.User{Username: "autor", Email: "autor@example.com", PasswordHash: "x"}
+	if err := db.Create(&author).Error; err != nil {
+		t.Fatal(err)
+	}
+	// Mensagens 1..10 no canal 1, intercaladas com mensagens do canal 2 que não
+	// podem aparecer nas páginas nem influenciar hasMore.
+	channelIDs := map[uint][]uint{}
+	for i := 0; i < 15; i++ {
+		channelID := uint(1)
+		if i%3 == 2 {
+			channelID = 2
+		}
+		message := models.Message{ChannelID: channelID, AuthorID: author.ID, Content: "m"}
+		if err := db.Create(&message).Error; err != nil {
+			t.Fatal(err)
+		}
+		channelIDs[channelID] = append(channelIDs[channelID], message.ID)
+	}
+	ids := channelIDs[1] // Em ordem crescente
+	if len(ids) != 10 {
+		t.Fatalf("esperado 10 mensagens no canal 1, obtido %d", len(ids))
+	}
+
+	tests := []struct {
+		name                  string
+		cursor                messageCursor
+		want                  []uint // Em ordem decrescente
+		wantBefore, wantAfter bool
+	}{
+		{"mais recentes", messageCursor{Limit: 3}, []uint{ids[9], ids[8], ids[7]}, true, false},
+		{"canal inteiro", messageCursor{Limit: 50}, reversed(ids), false, false},
+		{"before exclui o cursor", messageCursor{Limit: 3, Before: ids[5]}, []uint{ids[4], ids[3], ids[2]}, true, true},
+		{"before no início", messageCursor{Limit: 3, Before: ids[2]}, []uint{ids[1], ids[0]}, false, true},
+		{"after exclui o cursor", messageCursor{Limit: 3, After: ids[5]}, []uint{ids[8], ids[7], ids[6]}, true, true},
+		{"after no fim", messageCursor{Limit: 3, After: ids[7]}, []uint{ids[9], ids[8]}, true, false},
+		{"around inclui o alvo", messageCursor{Limit: 4, Around: ids[5]}, []uint{ids[7], ids[6], ids[5], ids[4]}, true, true},
+		{"around com limite ímpar", messageCursor{Limit: 3, Around: ids[5]}, []uint{ids[6], ids[5], ids[4]}, true, true},
+		{"around com limite 1", messageCursor{Limit: 1, Around: ids[5]}, []uint{ids[5]}, true, true},
+		{"around no fim", messageCursor{Limit: 4, Around: ids[9]}, []uint{ids[9], ids[8]}, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages, hasMoreBefore, hasMoreAfter, err := fetchMessagePage(db.Where("channel_id = ?", 1), tt.cursor)
+			if err != nil {
+				t.Fatalf("erro inesperado: %v", err)
+			}
+			got := make([]uint, len(messages))
+			for i, message := range messages {
+				got[i] = message.ID
+			}
+			if !equalIDs(got, tt.want) {
+				t.Errorf("mensagens = %v, esperado %v", got, tt.want)
+			}
+			if hasMoreBefore != tt.wantBefore || hasMoreAfter != tt.wantAfter {
+				t.Errorf("hasMore = %v/%v, esperado %v/%v", hasMoreBefore, hasMoreAfter, tt.wantBefore, tt.wantAfter)
+			}
+		})
+	}
+}
+
+func reversed(ids []uint) []uint {
+	out := make([]uint, len(ids))
+	for i, id := range ids {
+		out[len(ids)-1-i] = id
+	}
+	return out
+}
+
+func equalIDs(a, b []uint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,17 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// parseIDParam lê um parâmetro de rota numérico (ex: :serverId, :roleId).
+// Retorna false se o valor não for um ID válido.
+func parseIDParam(c *gin.Context, name string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 32)
+	if err != nil || id == 0 {
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,334 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gustavoverneck/discordia/server/models"
+	"gorm.io/gorm"
+)
+
+// Bits de permissão usados em models.Role.Permissions e nas sobrescritas de canal.
+const (
+	PermissionCreateInvite       int64 = 1 << 0
+	PermissionKickMembers        int64 = 1 << 1
+	PermissionBanMembers         int64 = 1 << 2
+	PermissionAdministrator      int64 = 1 << 3
+	PermissionManageChannels     int64 = 1 << 4
+	PermissionManageServer       int64 = 1 << 5
+	PermissionViewAuditLog       int64 = 1 << 7
+	PermissionViewChannel        int64 = 1 << 10
+	PermissionSendMessages       int64 = 1 << 11
+	PermissionManageMessages     int64 = 1 << 13
+	PermissionReadMessageHistory int64 = 1 << 16
+	PermissionManageNicknames    int64 = 1 << 27
+	PermissionManageRoles        int64 = 1 << 28
+	PermissionModerateMembers    int64 = 1 << 40
+
+	// PermissionAll reúne todos os bits conhecidos.
+	PermissionAll = PermissionCreateInvite | PermissionKickMembers | PermissionBanMembers |
+		PermissionAdministrator | PermissionManageChannels | PermissionManageServer | PermissionViewAuditLog |
+		PermissionViewChannel | PermissionSendMessages | PermissionManageMessages |
+		PermissionReadMessageHistory | PermissionManageNicknames | PermissionManageRoles |
+		PermissionModerateMembers
+
+	// PermissionDM são as permissões de todos os participantes de um DM ou DM em grupo.
+	PermissionDM = PermissionViewChannel | PermissionSendMessages | PermissionReadMessageHistory
+
+	// DefaultEveryonePermissions são as permissões do cargo @everyone de um servidor novo.
+	DefaultEveryonePermissions = PermissionViewChannel | PermissionSendMessages |
+		PermissionReadMessageHistory | PermissionCreateInvite
+)
+
+// Tipos de alvo de uma sobrescrita de permissão de canal.
+const (
+	OverwriteTargetRole   = "role"
+	OverwriteTargetMember = "member"
+)
+
+var errMissingPermission = errors.New("você não tem permissão para esta ação")
+
+// permissionContext reúne o que é preciso para calcular as permissões de um
+// membro em um servidor: o cargo @everyone, os cargos do membro e o resultado
+// base (sem sobrescritas de canal).
+type permissionContext struct {
+	server   models.Server
+	userID   uint
+	everyone models.Role
+	roles    []models.Role // Cargos atribuídos ao membro (sem o @everyone)
+	base     int64
+}
+
+// everyoneRole retorna o cargo @everyone do servidor, criando-o com as
+// permissões padrão para servidores criados antes da existência de cargos.
+func everyoneRole(db *gorm.DB, serverID uint) (models.Role, error) {
+	var role models.Role
+	err := db.Where(models.Role{ServerID: serverID, IsDefault: true}).
+		Attrs(models.Role{Name: "@everyone", Permissions: DefaultEveryonePermissions}).
+		FirstOrCreate(&role).Error
+	return role, err
+}
+
+// loadPermissionContext carrega o servidor, garante que o usuário é membro e
+// calcula suas permissões base.
+func loadPermissionContext(db *gorm.DB, userID, serverID uint) (*permissionContext, error) {
+	pc := &permissionContext{userID: userID}
+	if err := db.First(&pc.server, serverID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errServerNotFound
+		}
+		return nil, err
+	}
+
+	member, err := isServerMember(db, userID, serverID)
+	if err != nil {
+		return nil, err
+	}
+	if !member {
+		return nil, errNotServerMember
+	}
+
+	if pc.everyone, err = everyoneRole(db, serverID); err != nil {
+		return nil, err
+	}
+	err = db.Joins("JOIN member_roles ON member_roles.role_id = roles.id").
+		Where("member_roles.user_id = ? AND roles.server_id = ?", userID, serverID).
+		Find(&pc.roles).Error
+	if err != nil {
+		return nil, err
+	}
+
+	pc.base = pc.computeBase()
+	return pc, nil
+}
+
+// loadPermissionContexts é a versão de loadPermissionContext para vários
+// servidores dos quais o usuário já é membro: os cargos de todos eles são
+// buscados de uma vez, sem uma consulta por servidor.
+func loadPermissionContexts(db *gorm.DB, userID uint, servers []*models.Server) (map[uint]*permissionContext, error) {
+	serverIDs := make([]uint, len(servers))
+	for i, server := range servers {
+		serverIDs[i] = server.ID
+	}
+
+	var everyoneRoles []models.Role
+	if err := db.Where("server_id IN ? AND is_default = ?", serverIDs, true).Find(&everyoneRoles).Error; err != nil {
+		return nil, err
+	}
+	everyoneByServer := make(map[uint]models.Role, len(everyoneRoles))
+	for _, role := range everyoneRoles {
+		everyoneByServer[role.ServerID] = role
+	}
+
+	var memberRoles []models.Role
+	err := db.Joins("JOIN member_roles ON member_roles.role_id = roles.id").
+		Where("member_roles.user_id = ? AND roles.server_id IN ?", userID, serverIDs).
+		Find(&memberRoles).Error
+	if err != nil {
+		return nil, err
+	}
+	rolesByServer := make(map[uint][]models.Role)
+	for _, role := range memberRoles {
+		rolesByServer[role.ServerID] = append(rolesByServer[role.ServerID], role)
+	}
+
+	contexts := make(map[uint]*permissionContext, len(servers))
+	for _, server := range servers {
+		pc := &permissionContext{server: *server, userID: userID, roles: rolesByServer[server.ID]}
+		everyone, ok := everyoneByServer[server.ID]
+		if !ok {
+			// Servidor criado antes da existência de cargos
+			if everyone, err = everyoneRole(db, server.ID); err != nil {
+				return nil, err
+			}
+		}
+		pc.everyone = everyone
+		pc.base = pc.computeBase()
+		contexts[server.ID] = pc
+	}
+	return contexts, nil
+}
+
+func (pc *permissionContext) isOwner() bool {
+	return pc.server.OwnerID == pc.userID
+}
+
+func (pc *permissionContext) computeBase() int64 {
+	if pc.isOwner() {
+		return PermissionAll
+	}
+	perms := pc.everyone.Permissions
+	for _, role := range pc.roles {
+		perms |= role.Permissions
+	}
+	if perms&PermissionAdministrator != 0 {
+		return PermissionAll
+	}
+	return perms
+}
+
+// has informa se as permissões base incluem todos os bits de required.
+func (pc *permissionContext) has(required int64) bool {
+	return pc.base&required == required
+}
+
+// highestPosition é a posição do cargo mais alto do membro; o proprietário está acima de todos.
+func (pc *permissionContext) highestPosition() int {
+	if pc.isOwner() {
+		return int(^uint(0) >> 1)
+	}
+	highest := pc.everyone.Position
+	for _, role := range pc.roles {
+		if role.Position > highest {
+			highest = role.Position
+		}
+	}
+	return highest
+}
+
+// canManageRole informa se o membro pode editar, atribuir ou remover o cargo,
+// respeitando a hierarquia: apenas cargos abaixo do seu cargo mais alto.
+func (pc *permissionContext) canManageRole(role *models.Role) bool {
+	return pc.has(PermissionManageRoles) && (pc.isOwner() || role.Position < pc.highestPosition())
+}
+
+// canModerate informa se o membro pode expulsar ou banir o alvo: o dono nunca
+// pode ser moderado e, fora o dono, só quem tem um cargo mais alto que o do alvo.
+func (pc *permissionContext) canModerate(target *permissionContext) bool {
+	if target.isOwner() || target.userID == pc.userID {
+		return false
+	}
+	return pc.isOwner() || target.highestPosition() < pc.highestPosition()
+}
+
+// forChannel aplica as sobrescritas do canal sobre as permissões base, na ordem:
+// @everyone, cargos do membro (deny depois allow, agregados) e, por fim, o próprio membro.
+func (pc *permissionContext) forChannel(overwrites []models.PermissionOverwrite) int64 {
+	perms := pc.base
+	if perms&PermissionAdministrator != 0 {
+		return PermissionAll
+	}
+
+	memberRoles := make(map[uint]bool, len(pc.roles))
+	for _, role := range pc.roles {
+		memberRoles[role.ID] = true
+	}
+
+	var roleAllow, roleDeny int64
+	var memberOverwrite *models.PermissionOverwrite
+	for i := range overwrites {
+		ow := &overwrites[i]
+		switch {
+		case ow.TargetType == OverwriteTargetRole && ow.TargetID == pc.everyone.ID:
+			perms = (perms &^ ow.Deny) | ow.Allow
+		case ow.TargetType == OverwriteTargetRole && memberRoles[ow.TargetID]:
+			roleAllow |= ow.Allow
+			roleDeny |= ow.Deny
+		case ow.TargetType == OverwriteTargetMember && ow.TargetID == pc.userID:
+			memberOverwrite = ow
+		}
+	}
+	perms = (perms &^ roleDeny) | roleAllow
+	if memberOverwrite != nil {
+		perms = (perms &^ memberOverwrite.Deny) | memberOverwrite.Allow
+	}
+	return perms
+}
+
+// channelPermissions calcula as permissões efetivas do membro em um canal.
+func (pc *permissionContext) channelPermissions(db *gorm.DB, channel *models.Channel) (int64, error) {
+	if pc.base&PermissionAdministrator != 0 {
+		return PermissionAll, nil
+	}
+	var overwrites []models.PermissionOverwrite
+	if err := db.Where("channel_id = ?", channel.ID).Find(&overwrites).Error; err != nil {
+		return 0, err
+	}
+	return pc.forChannel(overwrites), nil
+}
+
+// serverChannelPermissions carrega os canais do servidor e calcula as permissões
+// efetivas do membro em cada um, buscando todas as sobrescritas de uma vez.
+func (pc *permissionContext) serverChannelPermissions(db *gorm.DB) ([]models.Channel, map[uint]int64, error) {
+	var channels []models.Channel
+	if err := db.Where("server_id = ?", pc.server.ID).Order("position ASC, id ASC").Find(&channels).Error; err != nil {
+		return nil, nil, err
+	}
+
+	var overwrites []models.PermissionOverwrite
+	if err := db.Joins("JOIN channels ON channels.id = permission_overwrites.channel_id").
+		Where("channels.server_id = ?", pc.server.ID).Find(&overwrites).Error; err != nil {
+		return nil, nil, err
+	}
+	overwritesByChannel := make(map[uint][]models.PermissionOverwrite)
+	for _, ow := range overwrites {
+		overwritesByChannel[ow.ChannelID] = append(overwritesByChannel[ow.ChannelID], ow)
+	}
+
+	perms := make(map[uint]int64, len(channels))
+	for _, channel := range channels {
+		perms[channel.ID] = pc.forChannel(overwritesByChannel[channel.ID])
+	}
+	return channels, perms, nil
+}
+
+// channelViewers calcula, para cada canal do servidor informado, quais membros
+// podem vê-lo. Cargos e sobrescritas são buscados de uma vez para todos os
+// membros, sem uma consulta por membro.
+func channelViewers(db *gorm.DB, serverID uint, channels []models.Channel) (map[uint][]uint, error) {
+	var server models.Server
+	if err := db.First(&server, serverID).Error; err != nil {
+		return nil, err
+	}
+	everyone, err := everyoneRole(db, serverID)
+	if err != nil {
+		return nil, err
+	}
+	memberIDs, err := serverMemberIDs(db, serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	var roles []models.Role
+	if err := db.Where("server_id = ?", serverID).Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	rolesByID := make(map[uint]models.Role, len(roles))
+	for _, role := range roles {
+		rolesByID[role.ID] = role
+	}
+	var memberRoles []models.MemberRole
+	if err := db.Where("server_id = ?", serverID).Find(&memberRoles).Error; err != nil {
+		return nil, err
+	}
+	rolesByMember := make(map[uint][]models.Role)
+	for _, mr := range memberRoles {
+		if role, ok := rolesByID[mr.RoleID]; ok {
+			rolesByMember[mr.UserID] = append(rolesByMember[mr.UserID], role)
+		}
+	}
+
+	channelIDs := make([]uint, len(channels))
+	for i, channel := range channels {
+		channelIDs[i] = channel.ID
+	}
+	var overwrites []models.PermissionOverwrite
+	if err := db.Where("channel_id IN ?", channelIDs).Find(&overwrites).Error; err != nil {
+		return nil, err
+	}
+	overwritesByChannel := make(map[uint][]models.PermissionOverwrite)
+	for _, ow := range overwrites {
+		overwritesByChannel[ow.ChannelID] = append(overwritesByChannel[ow.ChannelID], ow)
+	}
+
+	viewers := make(map[uint][]uint, len(channels))
+	for _, memberID := range memberIDs {
+		pc := &permissionContext{server: server, userID: memberID, everyone: everyone, roles: rolesByMember[memberID]}
+		pc.base = pc.computeBase()
+		for _, channel := range channels {
+			if pc.forChannel(overwritesByChannel[channel.ID])&PermissionViewChannel != 0 {
+				viewers[channel.ID] = append(viewers[channel.ID], memberID)
+			}
+		}
+	}
+	return viewers, nil
+}
@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/gustavoverneck/discordia/server/models"
+	"gorm.io/gorm"
+)
+
+const (
+	testOwnerID   uint = 1
+	testMemberID  uint = 2
+	testOtherID   uint = 3
+	testEveryone  uint = 10
+	testRoleMod   uint = 11
+	testRoleMuted uint = 12
+	testRoleOther uint = 13
+)
+
+func testRole(id uint, position int, perms int64) models.Role {
+	return models.Role{Model: gorm.Model{ID: id}, Position: position, Permissions: perms, IsDefault: id == testEveryone}
+}
+
+// newTestContext monta um permissionContext sem banco: o servidor pertence a
+// testOwnerID e o @everyone tem as permissões padrão.
+func newTestContext(userID uint, roles ...models.Role) *permissionContext {
+	pc := &permissionContext{
+		server:   models.Server{OwnerID: testOwnerID},
+		userID:   userID,
+		everyone: testRole(testEveryone, 0, DefaultEveryonePermissions),
+		roles:    roles,
+	}
+	pc.base = pc.computeBase()
+	return pc
+}
+
+func roleOverwrite(roleID uint, allow, deny int64) models.PermissionOverwrite {
+	return models.PermissionOverwrite{TargetType: OverwriteTargetRole, TargetID: roleID, Allow: allow, Deny: deny}
+}
+
+func memberOverwrite(userID uint, allow, deny int64) models.PermissionOverwrite {
+	return models.PermissionOverwrite{TargetType: OverwriteTargetMember, TargetID: userID, Allow: allow, Deny: deny}
+}
+
+func TestComputeBase(t *testing.T) {
+	tests := []struct {
+		name   string
+		userID uint
+		roles  []models.Role
+		want   int64
+	}{
+		{"somente @everyone", testMemberID, nil, DefaultEveryonePermissions},
+		{"cargos somam permissões", testMemberID,
+			[]models.Role{testRole(testRoleMod, 1, PermissionKickMembers), testRole(testRoleOther, 2, PermissionManageMessages)},
+			DefaultEveryonePermissions | PermissionKickMembers | PermissionManageMessages},
+		{"administrador recebe tudo", testMemberID, []models.Role{testRole(testRoleMod, 1, PermissionAdministrator)}, PermissionAll},
+		{"dono recebe tudo sem cargos", testOwnerID, nil, PermissionAll},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newTestContext(tt.userID, tt.roles...).base; got != tt.want {
+				t.Errorf("base = %b, esperado %b", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestForChannel(t *testing.T) {
+	mod := testRole(testRoleMod, 2, PermissionKickMembers)
+	muted := testRole(testRoleMuted, 1, 0)
+
+	tests := []struct {
+		name       string
+		userID     uint
+		roles      []models.Role
+		overwrites []models.PermissionOverwrite
+		want       int64 // Bits verificados
+		wantSet    bool  // Se os bits devem estar presentes
+	}{
+		{"sem sobrescritas vale a base", testMemberID, nil, nil,
+			PermissionViewChannel | PermissionSendMessages, true},
+		{"@everyone nega envio", testMemberID, nil,
+			[]models.PermissionOverwrite{roleOverwrite(testEveryone, 0, PermissionSendMessages)},
+			PermissionSendMessages, false},
+		{"cargo libera o que @everyone nega", testMemberID, []models.Role{mod},
+			[]models.PermissionOverwrite{
+				roleOverwrite(testEveryone, 0, PermissionViewChannel),
+				roleOverwrite(testRoleMod, PermissionViewChannel, 0),
+			},
+			PermissionViewChannel, true},
+		{"entre cargos, allow vence deny", testMemberID, []models.Role{mod, muted},
+			[]models.PermissionOverwrite{
+				roleOverwrite(testRoleMod, PermissionSendMessages, 0),
+				roleOverwrite(testRoleMuted, 0, PermissionSendMessages),
+			},
+			PermissionSendMessages, true},
+		{"membro nega o que o cargo libera", testMemberID, []models.Role{mod},
+			[]models.PermissionOverwrite{
+				roleOverwrite(testRoleMod, PermissionManageMessages, 0),
+				memberOverwrite(testMemberID, 0, PermissionManageMessages),
+			},
+			PermissionManageMessages, false},
+		{"membro libera o que o cargo nega", testMemberID, []models.Role{muted},
+			[]models.PermissionOverwrite{
+				roleOverwrite(testRoleMuted, 0, PermissionSendMessages),
+				memberOverwrite(testMemberID, PermissionSendMessages, 0),
+			},
+			PermissionSendMessages, true},
+		{"cargo que o membro não tem é ignorado", testMemberID, []models.Role{mod},
+			[]models.PermissionOverwrite{roleOverwrite(testRoleOther, 0, PermissionViewChannel)},
+			PermissionViewChannel, true},
+		{"sobrescrita de outro membro é ignorada", testMemberID, nil,
+			[]models.PermissionOverwrite{memberOverwrite(testOtherID, 0, PermissionViewChannel)},
+			PermissionViewChannel, true},
+		{"cargo com o mesmo ID do usuário não é sobrescrita de membro", testMemberID, nil,
+			[]models.PermissionOverwrite{roleOverwrite(testMemberID, 0, PermissionViewChannel)},
+			PermissionViewChannel, true},
+		{"administrador ignora negações", testMemberID, []models.Role{testRole(testRoleMod, 2, PermissionAdministrator)},
+			[]models.PermissionOverwrite{
+				roleOverwrite(testEveryone, 0, PermissionViewChannel),
+				memberOverwrite(testMemberID, 0, PermissionViewChannel),
+			},
+			PermissionAll, true},
+		{"dono ignora negações", testOwnerID, nil,
+			[]models.PermissionOverwrite{memberOverwrite(testOwnerID, 0, PermissionSendMessages)},
+			PermissionAll, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newTestContext(tt.userID, tt.roles...).forChannel(tt.overwrites)
+			if has := got&tt.want == tt.want; has != tt.wantSet {
+				t.Errorf("forChannel = %b; bits %b presentes = %v, esperado %v", got, tt.want, has, tt.wantSet)
+			}
+		})
+	}
+}
+
+func TestForChannelOrder(t *testing.T) {
+	// A ordem das sobrescritas na lista não altera o resultado.
+	pc := newTestContext(testMemberID, testRole(testRoleMod, 2, 0))
+	overwrites := []models.PermissionOverwrite{
+		memberOverwrite(testMemberID, 0, PermissionSendMessages),
+		roleOverwrite(testRoleMod, PermissionSendMessages, 0),
+		roleOverwrite(testEveryone, PermissionManageMessages, 0),
+	}
+	got := pc.forChannel(overwrites)
+	if got&PermissionSendMessages != 0 {
+		t.Errorf("a sobrescrita do membro deveria ser aplicada por último: %b", got)
+	}
+	if got&PermissionManageMessages == 0 {
+		t.Errorf("a sobrescrita do @everyone deveria ser aplicada: %b", got)
+	}
+}
+
+func TestHighestPosition(t *testing.T) {
+	tests := []struct {
+		name   string
+		userID uint
+		roles  []models.Role
+		want   int
+	}{
+		{"somente @everyone", testMemberID, nil, 0},
+		{"cargo mais alto", testMemberID, []models.Role{testRole(testRoleMuted, 1, 0), testRole(testRoleMod, 5, 0)}, 5},
+		{"dono acima de todos", testOwnerID, nil, int(^uint(0) >> 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newTestContext(tt.userID, tt.roles...).highestPosition(); got != tt.want {
+				t.Errorf("highestPosition = %d, esperado %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanModerate(t *testing.T) {
+	high := testRole(testRoleMod, 5, PermissionKickMembers)
+	low := testRole(testRoleMuted, 1, 0)
+	same := testRole(testRoleOther, 5, 0)
+
+	tests := []struct {
+		name      string
+		moderator *permissionContext
+		target    *permissionContext
+		want      bool
+	}{
+		{"cargo mais alto modera o mais baixo", newTestContext(testMemberID, high), newTestContext(testOtherID, low), true},
+		{"cargo mais baixo não modera", newTestContext(testMemberID, low), newTestContext(testOtherID, high), false},
+		{"mesma posição não modera", newTestContext(testMemberID, high), newTestContext(testOtherID, same), false},
+		{"dono modera qualquer membro", newTestContext(testOwnerID), newTestContext(testOtherID, high), true},
+		{"ninguém modera o dono", newTestContext(testMemberID, testRole(testRoleMod, 99, PermissionAdministrator)), newTestContext(testOwnerID), false},
+		{"ninguém modera a si mesmo", newTestContext(testMemberID, high), newTestContext(testMemberID, high), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.moderator.canModerate(tt.target); got != tt.want {
+				t.Errorf("canModerate = %v, esperado %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanManageRole(t *testing.T) {
+	manager := testRole(testRoleMod, 5, PermissionManageRoles)
+	below := testRole(testRoleMuted, 1, 0)
+	above := testRole(testRoleOther, 7, 0)
+
+	tests := []struct {
+		name string
+		pc   *permissionContext
+		role models.Role
+		want bool
+	}{
+		{"cargo abaixo do seu", newTestContext(testMemberID, manager), below, true},
+		{"o próprio cargo", newTestContext(testMemberID, manager), manager, false},
+		{"cargo acima do seu", newTestContext(testMemberID, manager), above, false},
+		{"sem MANAGE_ROLES", newTestContext(testMemberID, testRole(testRoleMod, 5, 0)), below, false},
+		{"dono gerencia qualquer cargo", newTestContext(testOwnerID), above, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.pc.canManageRole(&tt.role); got != tt.want {
+				t.Errorf("canManageRole = %v, esperado %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,353 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gustavoverneck/discordia/server/models"
+	"gorm.io/gorm"
+)
+
+// Status de presença. online, idle, dnd e invisible também são os status que o
+// usuário pode escolher (models.User.Status); offline é apenas calculado.
+const (
+	PresenceOnline    = "online"
+	PresenceIdle      = "idle"
+	PresenceDND       = "dnd"
+	PresenceInvisible = "invisible"
+	PresenceOffline   = "offline"
+)
+
+const (
+	// presenceIdleTimeout é o tempo sem comandos do cliente após o qual a sessão fica ausente.
+	presenceIdleTimeout = 5 * time.Minute
+	// customStatusSweepInterval é a frequência com que status personalizados expirados são removidos.
+	customStatusSweepInterval = time.Minute
+	maxCustomStatusLength     = 128
+)
+
+// presenceChange é uma mudança de presença calculada pelo Hub.
+type presenceChange struct {
+	userID uint
+	status string
+}
+
+// presenceQueue entrega as mudanças de presença do Hub ao PresenceHandler.
+// push nunca bloqueia, para que a goroutine do Hub não dependa do banco de dados.
+type presenceQueue struct {
+	mu      sync.Mutex
+	pending []presenceChange
+	signal  chan struct{}
+}
+
+func newPresenceQueue() *presenceQueue {
+	return &presenceQueue{signal: make(chan struct{}, 1)}
+}
+
+func (q *presenceQueue) push(change presenceChange) {
+	q.mu.Lock()
+	q.pending = append(q.pending, change)
+	q.mu.Unlock()
+	select {
+	case q.signal <- struct{}{}:
+	default: // Já há um aviso pendente
+	}
+}
+
+func (q *presenceQueue) drain() []presenceChange {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	changes := q.pending
+	q.pending = nil
+	return changes
+}
+
+// validStatusPreference informa se status pode ser escolhido pelo usuário.
+func validStatusPreference(status string) bool {
+	switch status {
+	case PresenceOnline, PresenceIdle, PresenceDND, PresenceInvisible:
+		return true
+	}
+	return false
+}
+
+// normalizeStatusPreference trata valores antigos ou vazios de models.User.Status como online.
+func normalizeStatusPreference(status string) string {
+	if validStatusPreference(status) {
+		return status
+	}
+	return PresenceOnline
+}
+
+type CustomStatusResponse struct {
+	Text      string     `json:"text"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// PresenceUpdatePayload é o corpo do evento presence_update e da listagem de presenças.
+type PresenceUpdatePayload struct {
+	UserID       uint                  `json:"userId"`
+	Status       string                `json:"status"`
+	CustomStatus *CustomStatusResponse `json:"customStatus,omitempty"`
+}
+
+// activeCustomStatus retorna o status personalizado do usuário, ou nil se não houver ou tiver expirado.
+func activeCustomStatus(user models.User, now time.Time) *CustomStatusResponse {
+	if user.CustomStatus == "" || (user.CustomStatusExpiresAt != nil && !user.CustomStatusExpiresAt.After(now)) {
+		return nil
+	}
+	return &CustomStatusResponse{Text: user.CustomStatus, ExpiresAt: user.CustomStatusExpiresAt}
+}
+
+func toPresencePayload(user models.User, status string) PresenceUpdatePayload {
+	payload := PresenceUpdatePayload{UserID: user.ID, Status: status}
+	if status != PresenceOffline {
+		// Usuários offline (ou invisíveis) não expõem o status personalizado
+		payload.CustomStatus = activeCustomStatus(user, time.Now())
+	}
+	return payload
+}
+
+// presenceAudience retorna os usuários que podem ver a presença de userID:
+// ele mesmo, quem divide algum servidor com ele e seus amigos.
+func presenceAudience(db *gorm.DB, userID uint) ([]uint, error) {
+	var userIDs []uint
+	err := db.Raw(`SELECT ? UNION
+		SELECT other.user_id FROM server_members AS mine
+			JOIN server_members AS other ON other.server_id = mine.server_id
+			WHERE mine.user_id = ?
+		UNION
+		SELECT target_id FROM relationships WHERE user_id = ? AND type = ?`,
+		userID, userID, userID, RelationshipFriend).
+		Scan(&userIDs).Error
+	return userIDs, err
+}
+
+// PresenceHandler publica as mudanças de presença e expõe as rotas de status.
+// Lembre-se de iniciar Run() em uma goroutine, assim como o Hub.
+type PresenceHandler struct {
+	DB  *gorm.DB
+	Hub *Hub
+}
+
+func NewPresenceHandler(db *gorm.DB, hub *Hub) *PresenceHandler {
+	return &PresenceHandler{DB: db, Hub: hub}
+}
+
+// Run envia presence_update para cada mudança calculada pelo Hub e remove
+// periodicamente os status personalizados expirados.
+func (ph *PresenceHandler) Run() {
+	sweep := time.NewTicker(customStatusSweepInterval)
+	defer sweep.Stop()
+
+	for {
+		select {
+		case <-ph.Hub.presence.signal:
+			for _, change := range ph.Hub.presence.drain() {
+				ph.publish(change.userID, change.status)
+			}
+		case now := <-sweep.C:
+			ph.expireCustomStatuses(now)
+		}
+	}
+}
+
+// publish envia o presence_update de userID para todos que podem vê-lo.
+func (ph *PresenceHandler) publish(userID uint, status string) {
+	var user models.User
+	if err := ph.DB.First(&user, userID).Error; err != nil {
+		log.Printf("Erro ao buscar usuário %d para atualizar presença: %v", userID, err)
+		return
+	}
+	audience, err := presenceAudience(ph.DB, userID)
+	if err != nil {
+		log.Printf("Erro ao buscar destinatários da presença do usuário %d: %v", userID, err)
+		return
+	}
+	ph.Hub.DispatchToUsers(audience, "presence_update", toPresencePayload(user, status))
+}
+
+// republish reenvia a presença atual de userID, ex: após mudar o status personalizado.
+func (ph *PresenceHandler) republish(userID uint) {
+	if status := ph.Hub.Presences([]uint{userID})[userID]; status != PresenceOffline {
+		ph.publish(userID, status)
+	}
+}
+
+func (ph *PresenceHandler) expireCustomStatuses(now time.Time) {
+	var userIDs []uint
+	err := ph.DB.Model(&models.User{}).
+		Where("custom_status <> '' AND custom_status_expires_at <= ?", now).
+		Pluck("id", &userIDs).Error
+	if err != nil {
+		log.Printf("Erro ao buscar status personalizados expirados: %v", err)
+		return
+	}
+	if len(userIDs) == 0 {
+		return
+	}
+	err = ph.DB.Model(&models.User{}).Where("id IN ?", userIDs).
+		Updates(map[string]interface{}{"custom_status": "", "custom_status_expires_at": nil}).Error
+	if err != nil {
+		log.Printf("Erro ao remover status personalizados expirados: %v", err)
+		return
+	}
+	for _, userID := range userIDs {
+		ph.republish(userID)
+	}
+}
+
+// setStatusPreference grava o status escolhido pelo usuário e recalcula sua presença.
+func setStatusPreference(db *gorm.DB, hub *Hub, userID uint, status string) error {
+	if err := db.Model(&models.User{}).Where("id = ?", userID).Update("status", status).Error; err != nil {
+		return err
+	}
+	hub.SetStatus(userID, status)
+	return nil
+}
+
+type UpdateStatusRequest struct {
+	Status string `json:"status" binding:"required,oneof=online idle dnd invisible"`
+}
+
+type UpdateCustomStatusRequest struct {
+	Text      string     `json:"text" binding:"required"`
+	ExpiresAt *time.Time `json:"expiresAt"` // Ausente = não expira
+}
+
+// ListPresences lista a presença de todos os usuários visíveis para o usuário
+// (membros dos seus servidores e amigos) que não estão offline.
+func (ph *PresenceHandler) ListPresences(c *gin.Context) {
+	rawUserID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+	userID := rawUserID.(uint)
+
+	audience, err := presenceAudience(ph.DB, userID)
+	if err != nil {
+		log.Printf("Erro ao buscar usuários visíveis para %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao buscar presenças."})
+		return
+	}
+
+	statuses := ph.Hub.Presences(audience)
+	var onlineIDs []uint
+	for id, status := range statuses {
+		if status != PresenceOffline {
+			onlineIDs = append(onlineIDs, id)
+		}
+	}
+
+	presences := []PresenceUpdatePayload{}
+	if len(onlineIDs) > 0 {
+		var users []models.User
+		if err := ph.DB.Where("id IN ?", onlineIDs).Order("id").Find(&users).Error; err != nil {
+			log.Printf("Erro ao buscar usuários online: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao buscar presenças."})
+			return
+		}
+		for _, user := range users {
+			presences = append(presences, toPresencePayload(user, statuses[user.ID]))
+		}
+	}
+	c.JSON(http.StatusOK, presences)
+}
+
+// UpdateStatus altera o status escolhido pelo usuário (online, idle, dnd ou invisible).
+func (ph *PresenceHandler) UpdateStatus(c *gin.Context) {
+	rawUserID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+	userID := rawUserID.(uint)
+
+	var req UpdateStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos: " + err.Error()})
+		return
+	}
+
+	if err := setStatusPreference(ph.DB, ph.Hub, userID, req.Status); err != nil {
+		log.Printf("Erro ao atualizar status do usuário %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao atualizar status."})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": req.Status})
+}
+
+// UpdateCustomStatus define o status personalizado do usuário, com expiração opcional.
+func (ph *PresenceHandler) UpdateCustomStatus(c *gin.Context) {
+	rawUserID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+	userID := rawUserID.(uint)
+
+	var req UpdateCustomStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos: " + err.Error()})
+		return
+	}
+	req.Text = strings.TrimSpace(req.Text)
+	if req.Text == "" || len([]rune(req.Text)) > maxCustomStatusLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "O status personalizado deve ter entre 1 e 128 caracteres."})
+		return
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A data de expiração deve estar no futuro."})
+		return
+	}
+
+	err := ph.DB.Model(&models.User{}).Where("id = ?", userID).
+		Updates(map[string]interface{}{"custom_status": req.Text, "custom_status_expires_at": req.ExpiresAt}).Error
+	if err != nil {
+		log.Printf("Erro ao atualizar status personalizado do usuário %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao atualizar status personalizado."})
+		return
+	}
+
+	ph.republish(userID)
+	c.JSON(http.StatusOK, CustomStatusResponse{Text: req.Text, ExpiresAt: req.ExpiresAt})
+}
+
+// ClearCustomStatus remove o status personalizado do usuário.
+func (ph *PresenceHandler) ClearCustomStatus(c *gin.Context) {
+	rawUserID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+	userID := rawUserID.(uint)
+
+	err := ph.DB.Model(&models.User{}).Where("id = ?", userID).
+		Updates(map[string]interface{}{"custom_status": "", "custom_status_expires_at": nil}).Error
+	if err != nil {
+		log.Printf("Erro ao remover status personalizado do usuário %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao remover status personalizado."})
+		return
+	}
+
+	ph.republish(userID)
+	c.Status(http.StatusNoContent)
+}
+
+// loadStatusPreference busca o status escolhido pelo usuário para iniciar sua sessão.
+func loadStatusPreference(db *gorm.DB, userID uint) string {
+	var user models.User
+	if err := db.Select("id", "status").First(&user, userID).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("Erro ao buscar status do usuário %d: %v", userID, err)
+		}
+		return PresenceOnline
+	}
+	return normalizeStatusPreference(user.Status)
+}
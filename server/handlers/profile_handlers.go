@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gustavoverneck/discordia/server/models"
+	"gorm.io/gorm"
+)
+
+// accentColorPattern valida a cor de destaque do perfil (#rrggbb).
+var accentColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// UserProfileResponse é o perfil público de um usuário, visível a qualquer
+// usuário autenticado. Também é o corpo do evento user_update.
+type UserProfileResponse struct {
+	ID          uint      `json:"id"`
+	Username    string    `json:"username"`
+	DisplayName string    `json:"displayName,omitempty"`
+	AvatarURL   string    `json:"avatarUrl,omitempty"`
+	BannerURL   string    `json:"bannerUrl,omitempty"`
+	Bio         string    `json:"bio,omitempty"`
+	Pronouns    string    `json:"pronouns,omitempty"`
+	AccentColor string    `json:"accentColor,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+func toUserProfile(user models.User) UserProfileResponse {
+	return UserProfileResponse{
+		ID:          user.ID,
+		Username:    user.Username,
+		DisplayName: user.DisplayName,
+		AvatarURL:   user.AvatarURL,
+		BannerURL:   user.BannerURL,
+		Bio:         user.Bio,
+		Pronouns:    user.Pronouns,
+		AccentColor: user.AccentColor,
+		CreatedAt:   user.CreatedAt,
+	}
+}
+
+// ownProfileResponse é o perfil completo do próprio usuário (GET /profile),
+// incluindo os dados privados.
+func ownProfileResponse(user models.User) gin.H {
+	return gin.H{
+		"id":           user.ID,
+		"username":     user.Username,
+		"email":        user.Email,
+		"avatarURL":    user.AvatarURL,
+		"status":       user.Status,
+		"customStatus": activeCustomStatus(user, time.Now()),
+		"displayName":  user.DisplayName,
+		"bio":          user.Bio,
+		"pronouns":     user.Pronouns,
+		"bannerURL":    user.BannerURL,
+		"accentColor":  user.AccentColor,
+		"createdAt":    user.CreatedAt,
+		"updatedAt":    user.UpdatedAt,
+	}
+}
+
+// dispatchUserUpdate envia o perfil público atualizado ao próprio usuário e a
+// quem o vê: membros dos mesmos servidores e amigos.
+func (uh *UserHandler) dispatchUserUpdate(user models.User) {
+	audience, err := presenceAudience(uh.DB, user.ID)
+	if err != nil {
+		log.Printf("Erro ao buscar destinatários do user_update do usuário %d: %v", user.ID, err)
+		return
+	}
+	uh.Hub.DispatchToUsers(audience, "user_update", toUserProfile(user))
+}
+
+// GetUserProfile lida com GET /users/:userId, o perfil público de um usuário.
+func (uh *UserHandler) GetUserProfile(c *gin.Context) {
+	if _, exists := c.Get("userID"); !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+
+	targetID, ok := parseIDParam(c, "userId")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID do usuário inválido"})
+		return
+	}
+
+	var user models.User
+	if err := uh.DB.Omit("password_hash", "email").First(&user, targetID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Usuário não encontrado."})
+			return
+		}
+		log.Printf("Erro ao buscar perfil do usuário %d: %v", targetID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar perfil do usuário."})
+		return
+	}
+	c.JSON(http.StatusOK, toUserProfile(user))
+}
+
+// profileImage liga um tipo de imagem ao campo do perfil onde sua URL é gravada.
+type profileImage struct {
+	kind      imageKind
+	formField string // Campo do formulário multipart
+	column    string
+	url       func(user *models.User) *string
+}
+
+var (
+	avatarProfileImage = profileImage{kind: avatarImage, formField: "avatarFile", column: "avatar_url",
+		url: func(user *models.User) *string { return &user.AvatarURL }}
+	bannerProfileImage = profileImage{kind: bannerImage, formField: "bannerFile", column: "banner_url",
+		url: func(user *models.User) *string { return &user.BannerURL }}
+)
+
+// UploadAvatar lida com PUT /profile/avatar (multipart, campo avatarFile).
+func (uh *UserHandler) UploadAvatar(c *gin.Context) {
+	uh.updateProfileImage(c, avatarProfileImage, true)
+}
+
+// DeleteAvatar lida com DELETE /profile/avatar.
+func (uh *UserHandler) DeleteAvatar(c *gin.Context) {
+	uh.updateProfileImage(c, avatarProfileImage, false)
+}
+
+// UploadBanner lida com PUT /profile/banner (multipart, campo bannerFile).
+func (uh *UserHandler) UploadBanner(c *gin.Context) {
+	uh.updateProfileImage(c, bannerProfileImage, true)
+}
+
+// DeleteBanner lida com DELETE /profile/banner.
+func (uh *UserHandler) DeleteBanner(c *gin.Context) {
+	uh.updateProfileImage(c, bannerProfileImage, false)
+}
+
+// updateProfileImage substitui (upload) ou remove a imagem do perfil. A imagem
+// anterior é apagada do armazenamento.
+func (uh *UserHandler) updateProfileImage(c *gin.Context, img profileImage, upload bool) {
+	rawUserID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+	userID := rawUserID.(uint)
+
+	var user models.User
+	if err := uh.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Usuário não encontrado"})
+		return
+	}
+
+	var newURL string
+	if upload {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImageUploadSize+1024*1024)
+		fileHeader, err := c.FormFile(img.formField)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Envie a imagem no campo " + img.formField + " (máx 5MB)."})
+			return
+		}
+		newURL, err = storeImage(c.Request.Context(), uh.Storage, img.kind, fileHeader)
+		if err != nil {
+			if message, ok := imageErrorMessage(err); ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": message})
+				return
+			}
+			log.Printf("Erro ao armazenar imagem do perfil do usuário %d: %v", userID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao armazenar a imagem."})
+			return
+		}
+	}
+
+	oldURL := *img.url(&user)
+	if err := uh.DB.Model(&user).Update(img.column, newURL).Error; err != nil {
+		deleteImage(c.Request.Context(), uh.Storage, newURL)
+		log.Printf("Erro ao salvar imagem do perfil do usuário %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao salvar as alterações do perfil."})
+		return
+	}
+	*img.url(&user) = newURL
+	if oldURL != "" && oldURL != newURL {
+		deleteImage(c.Request.Context(), uh.Storage, oldURL)
+	}
+
+	uh.dispatchUserUpdate(user)
+	c.JSON(http.StatusOK, ownProfileResponse(user))
+}
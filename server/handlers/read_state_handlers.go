@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gustavoverneck/discordia/server/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AckMessagePayload é o corpo do comando ack_message e do evento message_ack,
+// que sincroniza o estado de leitura entre os dispositivos do usuário.
+type AckMessagePayload struct {
+	ChannelID    uint `json:"channelId"`
+	MessageID    uint `json:"messageId"`
+	MentionCount int  `json:"mentionCount"`
+}
+
+// ChannelReadState resume o estado de leitura de um canal para o usuário.
+type ChannelReadState struct {
+	LastMessageID     *uint `json:"lastMessageId,omitempty"` // Última mensagem do canal
+	LastReadMessageID uint  `json:"lastReadMessageId"`       // 0 = nada lido ainda
+	Unread            bool  `json:"unread"`
+	MentionCount      int   `json:"mentionCount"`
+}
+
+// ChannelWithReadState é um canal de servidor acompanhado do estado de leitura do usuário.
+type ChannelWithReadState struct {
+	models.Channel
+	ChannelReadState
+}
+
+// loadReadStates calcula o estado de leitura do usuário em cada canal informado.
+func loadReadStates(db *gorm.DB, userID uint, channelIDs []uint) (map[uint]ChannelReadState, error) {
+	states := make(map[uint]ChannelReadState, len(channelIDs))
+	if len(channelIDs) == 0 {
+		return states, nil
+	}
+
+	var lastMessages []struct {
+		ChannelID     uint
+		LastMessageID uint
+	}
+	err := db.Model(&models.Message{}).
+		Select("channel_id, MAX(id) AS last_message_id").
+		Where("channel_id IN ?", channelIDs).
+		Group("channel_id").
+		Scan(&lastMessages).Error
+	if err != nil {
+		return nil, err
+	}
+	var readStates []models.ReadState
+	if err := db.Where("user_id = ? AND channel_id IN ?", userID, channelIDs).Find(&readStates).Error; err != nil {
+		return nil, err
+	}
+
+	for _, rs := range readStates {
+		states[rs.ChannelID] = ChannelReadState{LastReadMessageID: rs.LastMessageID, MentionCount: rs.MentionCount}
+	}
+	for _, last := range lastMessages {
+		state := states[last.ChannelID]
+		lastMessageID := last.LastMessageID
+		state.LastMessageID = &lastMessageID
+		state.Unread = lastMessageID > state.LastReadMessageID
+		states[last.ChannelID] = state
+	}
+	return states, nil
+}
+
+// ServerReadState resume o estado de leitura dos canais que o usuário pode ver em um servidor.
+type ServerReadState struct {
+	Unread       bool `json:"unread"`       // Algum canal visível tem mensagens não lidas
+	MentionCount int  `json:"mentionCount"` // Menções não lidas somadas de todos os canais
+}
+
+// serverReadStates calcula o ServerReadState de cada servidor informado, dos
+// quais o usuário é membro. Cargos, canais, sobrescritas e estados de leitura
+// são buscados de uma vez para todos os servidores.
+func serverReadStates(db *gorm.DB, userID uint, servers []*models.Server) (map[uint]ServerReadState, error) {
+	summaries := make(map[uint]ServerReadState, len(servers))
+	if len(servers) == 0 {
+		return summaries, nil
+	}
+	contexts, err := loadPermissionContexts(db, userID, servers)
+	if err != nil {
+		return nil, err
+	}
+	serverIDs := make([]uint, 0, len(servers))
+	for _, server := range servers {
+		serverIDs = append(serverIDs, server.ID)
+	}
+
+	var channels []models.Channel
+	if err := db.Where("server_id IN ?", serverIDs).Find(&channels).Error; err != nil {
+		return nil, err
+	}
+	var overwrites []models.PermissionOverwrite
+	if err := db.Joins("JOIN channels ON channels.id = permission_overwrites.channel_id").
+		Where("channels.server_id IN ?", serverIDs).Find(&overwrites).Error; err != nil {
+		return nil, err
+	}
+	overwritesByChannel := make(map[uint][]models.PermissionOverwrite)
+	for _, ow := range overwrites {
+		overwritesByChannel[ow.ChannelID] = append(overwritesByChannel[ow.ChannelID], ow)
+	}
+
+	const required = PermissionViewChannel | PermissionReadMessageHistory
+	serverByChannel := make(map[uint]uint, len(channels))
+	var visibleIDs []uint
+	for _, channel := range channels {
+		pc := contexts[*channel.ServerID]
+		if pc.forChannel(overwritesByChannel[channel.ID])&required == required {
+			serverByChannel[channel.ID] = *channel.ServerID
+			visibleIDs = append(visibleIDs, channel.ID)
+		}
+	}
+
+	states, err := loadReadStates(db, userID, visibleIDs)
+	if err != nil {
+		return nil, err
+	}
+	for channelID, state := range states {
+		serverID := serverByChannel[channelID]
+		summary := summaries[serverID]
+		summary.Unread = summary.Unread || state.Unread
+		summary.MentionCount += state.MentionCount
+		summaries[serverID] = summary
+	}
+	return summaries, nil
+}
+
+// ackMessage marca o canal como lido até messageID (inclusive). Também pode
+// voltar o marcador, para marcar mensagens como não lidas. O contador de
+// menções é recalculado a partir das menções após o novo marcador.
+func ackMessage(db *gorm.DB, userID, channelID, messageID uint) (*models.ReadState, error) {
+	var mentionCount int64
+	err := db.Table("message_mentions").
+		Joins("JOIN messages ON messages.id = message_mentions.message_id").
+		Where("message_mentions.user_id = ? AND messages.channel_id = ? AND messages.id > ? AND messages.deleted_at IS NULL",
+			userID, channelID, messageID).
+		Count(&mentionCount).Error
+	if err != nil {
+		return nil, err
+	}
+
+	state := models.ReadState{
+		UserID:        userID,
+		ChannelID:     channelID,
+		LastMessageID: messageID,
+		MentionCount:  int(mentionCount),
+	}
+	err = db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "channel_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"last_message_id", "mention_count", "updated_at"}),
+	}).Create(&state).Error
+	if err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// adjustMentionCounts soma delta (+1 ou -1) ao contador de menções dos usuários
+// que ainda não leram a mensagem.
+func adjustMentionCounts(tx *gorm.DB, message *models.Message, userIDs []uint, delta int) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	if delta < 0 {
+		return tx.Model(&models.ReadState{}).
+			Where("channel_id = ? AND user_id IN ? AND last_message_id < ? AND mention_count > 0", message.ChannelID, userIDs, message.ID).
+			Update("mention_count", gorm.Expr("mention_count - 1")).Error
+	}
+
+	states := make([]models.ReadState, 0, len(userIDs))
+	for _, userID := range userIDs {
+		states = append(states, models.ReadState{UserID: userID, ChannelID: message.ChannelID, MentionCount: 1})
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "channel_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"mention_count": gorm.Expr("read_states.mention_count + 1")}),
+		Where:     clause.Where{Exprs: []clause.Expression{gorm.Expr("read_states.last_message_id < ?", message.ID)}},
+	}).Create(&states).Error
+}
+
+// acknowledge valida o ack do usuário, grava o estado de leitura e o
+// sincroniza com os outros dispositivos do usuário.
+func acknowledge(db *gorm.DB, hub *Hub, userID, channelID, messageID uint) (*AckMessagePayload, error) {
+	if _, _, err := authorizeChannel(db, userID, channelID, PermissionViewChannel|PermissionReadMessageHistory); err != nil {
+		return nil, err
+	}
+	if _, err := findChannelMessage(db, channelID, messageID); err != nil {
+		return nil, err
+	}
+	state, err := ackMessage(db, userID, channelID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	payload := &AckMessagePayload{ChannelID: channelID, MessageID: messageID, MentionCount: state.MentionCount}
+	hub.DispatchToUsers([]uint{userID}, "message_ack", payload)
+	return payload, nil
+}
+
+// AckMessage lida com POST /channels/:channelId/messages/:messageId/ack.
+func (ch *ChannelHandler) AckMessage(c *gin.Context) {
+	rawUserID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+	userID := rawUserID.(uint)
+
+	channelID, ok := parseIDParam(c, "channelId")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID do canal inválido"})
+		return
+	}
+	messageID, ok := parseIDParam(c, "messageId")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID da mensagem inválido"})
+		return
+	}
+
+	payload, err := acknowledge(ch.DB, ch.Hub, userID, channelID, messageID)
+	if err != nil {
+		respondAuthzError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, payload)
+}
@@ -0,0 +1,384 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gustavoverneck/discordia/server/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Tipos de relacionamento (models.Relationship.Type).
+const (
+	RelationshipFriend          = "FRIEND"
+	RelationshipBlocked         = "BLOCKED"
+	RelationshipPendingIncoming = "PENDING_INCOMING"
+	RelationshipPendingOutgoing = "PENDING_OUTGOING"
+)
+
+var (
+	errRelationshipSelf     = errors.New("você não pode se adicionar como amigo")
+	errAlreadyFriends       = errors.New("vocês já são amigos")
+	errTargetBlocked        = errors.New("desbloqueie este usuário antes de enviar um pedido de amizade")
+	errFriendRequestBlocked = errors.New("não foi possível enviar o pedido de amizade")
+	errRelationshipNotFound = errors.New("relacionamento não encontrado")
+	errUserBlocked          = errors.New("não é possível enviar mensagens para este usuário")
+)
+
+type RelationshipHandler struct {
+	DB  *gorm.DB
+	Hub *Hub
+}
+
+func NewRelationshipHandler(db *gorm.DB, hub *Hub) *RelationshipHandler {
+	return &RelationshipHandler{DB: db, Hub: hub}
+}
+
+type FriendRequestRequest struct {
+	Username string `json:"username" binding:"required"`
+}
+
+type UpdateRelationshipRequest struct {
+	// FRIEND envia ou aceita um pedido de amizade; BLOCKED bloqueia o usuário.
+	Type string `json:"type" binding:"omitempty,oneof=FRIEND BLOCKED"`
+}
+
+type RelationshipResponse struct {
+	ID    uint        `json:"id"` // ID do outro usuário
+	Type  string      `json:"type"`
+	User  UserSummary `json:"user"`
+	Since time.Time   `json:"since"`
+}
+
+// RelationshipRemovePayload é o corpo do evento relationship_remove.
+type RelationshipRemovePayload struct {
+	ID   uint   `json:"id"`
+	Type string `json:"type"` // Tipo que o relacionamento tinha
+}
+
+func toRelationshipResponse(rel models.Relationship, target models.User) RelationshipResponse {
+	return RelationshipResponse{
+		ID:    rel.TargetID,
+		Type:  rel.Type,
+		User:  toUserSummary(target),
+		Since: rel.CreatedAt,
+	}
+}
+
+// loadRelationshipPair retorna as linhas de relacionamento de userID com targetID
+// e de targetID com userID (nil quando não existem).
+func loadRelationshipPair(db *gorm.DB, userID, targetID uint) (mine, theirs *models.Relationship, err error) {
+	var rows []models.Relationship
+	err = db.Where("(user_id = ? AND target_id = ?) OR (user_id = ? AND target_id = ?)", userID, targetID, targetID, userID).
+		Find(&rows).Error
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range rows {
+		if rows[i].UserID == userID {
+			mine = &rows[i]
+		} else {
+			theirs = &rows[i]
+		}
+	}
+	return mine, theirs, nil
+}
+
+// putRelationship grava (ou substitui) a linha de relacionamento.
+func putRelationship(tx *gorm.DB, rel *models.Relationship) error {
+	rel.CreatedAt = time.Now()
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "target_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"type", "created_at"}),
+	}).Create(rel).Error
+}
+
+// isBlockedBetween informa se algum dos dois usuários bloqueou o outro.
+func isBlockedBetween(db *gorm.DB, userID, otherID uint) (bool, error) {
+	var count int64
+	err := db.Model(&models.Relationship{}).
+		Where("type = ?", RelationshipBlocked).
+		Where("(user_id = ? AND target_id = ?) OR (user_id = ? AND target_id = ?)", userID, otherID, otherID, userID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// hasBlockAmongRecipients informa se algum participante do DM bloqueou outro participante.
+func hasBlockAmongRecipients(db *gorm.DB, channelID uint) (bool, error) {
+	recipients := db.Table("channel_recipients").Select("user_id").Where("channel_id = ?", channelID)
+	var count int64
+	err := db.Model(&models.Relationship{}).
+		Where("type = ? AND user_id IN (?) AND target_id IN (?)", RelationshipBlocked, recipients, recipients).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// requestFriendship envia um pedido de amizade de userID para targetID ou, se
+// targetID já tiver enviado um, aceita-o. Retorna a linha de userID e as linhas
+// alteradas (vazio quando o pedido já estava pendente).
+func requestFriendship(db *gorm.DB, userID, targetID uint) (*models.Relationship, []models.Relationship, error) {
+	if userID == targetID {
+		return nil, nil, errRelationshipSelf
+	}
+	var mine *models.Relationship
+	var changed []models.Relationship
+	err := db.Transaction(func(tx *gorm.DB) error {
+		current, theirs, err := loadRelationshipPair(tx, userID, targetID)
+		if err != nil {
+			return err
+		}
+		switch {
+		case current != nil && current.Type == RelationshipBlocked:
+			return errTargetBlocked
+		case theirs != nil && theirs.Type == RelationshipBlocked:
+			return errFriendRequestBlocked
+		case current != nil && current.Type == RelationshipFriend:
+			return errAlreadyFriends
+		case current != nil && current.Type == RelationshipPendingOutgoing:
+			mine = current // Pedido já enviado
+			return nil
+		case current != nil && current.Type == RelationshipPendingIncoming:
+			changed = []models.Relationship{
+				{UserID: userID, TargetID: targetID, Type: RelationshipFriend},
+				{UserID: targetID, TargetID: userID, Type: RelationshipFriend},
+			}
+		default:
+			changed = []models.Relationship{
+				{UserID: userID, TargetID: targetID, Type: RelationshipPendingOutgoing},
+				{UserID: targetID, TargetID: userID, Type: RelationshipPendingIncoming},
+			}
+		}
+		for i := range changed {
+			if err := putRelationship(tx, &changed[i]); err != nil {
+				return err
+			}
+		}
+		mine = &changed[0]
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return mine, changed, nil
+}
+
+// respondRelationshipError escreve a resposta HTTP para os erros de relacionamento.
+func respondRelationshipError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errRelationshipSelf), errors.Is(err, errTargetBlocked), errors.Is(err, errFriendRequestBlocked):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, errAlreadyFriends):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, errRelationshipNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		log.Printf("Erro ao atualizar relacionamento: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao atualizar relacionamento."})
+	}
+}
+
+// dispatchRelationships envia relationship_add para o dono de cada linha alterada.
+// users deve conter os dois usuários envolvidos.
+func (rh *RelationshipHandler) dispatchRelationships(changed []models.Relationship, users map[uint]models.User) {
+	for _, rel := range changed {
+		rh.Hub.DispatchToUsers([]uint{rel.UserID}, "relationship_add", toRelationshipResponse(rel, users[rel.TargetID]))
+	}
+}
+
+// ListRelationships lista amigos, pedidos pendentes e bloqueios do usuário.
+func (rh *RelationshipHandler) ListRelationships(c *gin.Context) {
+	rawUserID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+	userID := rawUserID.(uint)
+
+	var relationships []models.Relationship
+	if err := rh.DB.Preload("Target").Where("user_id = ?", userID).Order("created_at DESC").Find(&relationships).Error; err != nil {
+		log.Printf("Erro ao listar relacionamentos do usuário %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao buscar relacionamentos."})
+		return
+	}
+
+	relationshipResponses := []RelationshipResponse{}
+	for _, rel := range relationships {
+		relationshipResponses = append(relationshipResponses, toRelationshipResponse(rel, rel.Target))
+	}
+	c.JSON(http.StatusOK, relationshipResponses)
+}
+
+// SendFriendRequest envia um pedido de amizade pelo nome de usuário.
+func (rh *RelationshipHandler) SendFriendRequest(c *gin.Context) {
+	rawUserID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+	userID := rawUserID.(uint)
+
+	var req FriendRequestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos: " + err.Error()})
+		return
+	}
+
+	var target models.User
+	if err := rh.DB.Where("username = ?", req.Username).First(&target).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Usuário não encontrado"})
+			return
+		}
+		log.Printf("Erro ao buscar usuário %q: %v", req.Username, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar usuário."})
+		return
+	}
+	rh.befriend(c, userID, target)
+}
+
+// UpdateRelationship envia/aceita um pedido de amizade (type FRIEND, padrão) ou
+// bloqueia o usuário (type BLOCKED).
+func (rh *RelationshipHandler) UpdateRelationship(c *gin.Context) {
+	rawUserID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+	userID := rawUserID.(uint)
+
+	targetID, ok := parseIDParam(c, "userId")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID do usuário inválido"})
+		return
+	}
+
+	var req UpdateRelationshipRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos: " + err.Error()})
+			return
+		}
+	}
+
+	var target models.User
+	if err := rh.DB.First(&target, targetID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Usuário não encontrado"})
+			return
+		}
+		log.Printf("Erro ao buscar usuário %d: %v", targetID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar usuário."})
+		return
+	}
+
+	if req.Type == RelationshipBlocked {
+		rh.block(c, userID, target)
+		return
+	}
+	rh.befriend(c, userID, target)
+}
+
+func (rh *RelationshipHandler) befriend(c *gin.Context, userID uint, target models.User) {
+	var user models.User
+	if err := rh.DB.First(&user, userID).Error; err != nil {
+		log.Printf("Erro ao buscar usuário %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar usuário."})
+		return
+	}
+
+	mine, changed, err := requestFriendship(rh.DB, userID, target.ID)
+	if err != nil {
+		respondRelationshipError(c, err)
+		return
+	}
+	rh.dispatchRelationships(changed, map[uint]models.User{user.ID: user, target.ID: target})
+	c.JSON(http.StatusOK, toRelationshipResponse(*mine, target))
+}
+
+// block bloqueia target: desfaz amizade ou pedidos pendentes entre os dois e
+// impede novos pedidos e mensagens diretas dele.
+func (rh *RelationshipHandler) block(c *gin.Context, userID uint, target models.User) {
+	if userID == target.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Você não pode bloquear a si mesmo."})
+		return
+	}
+
+	blocked := models.Relationship{UserID: userID, TargetID: target.ID, Type: RelationshipBlocked}
+	var removed *models.Relationship
+	err := rh.DB.Transaction(func(tx *gorm.DB) error {
+		_, theirs, err := loadRelationshipPair(tx, userID, target.ID)
+		if err != nil {
+			return err
+		}
+		// Um bloqueio do outro lado continua valendo; o resto é desfeito
+		if theirs != nil && theirs.Type != RelationshipBlocked {
+			if err := tx.Delete(theirs).Error; err != nil {
+				return err
+			}
+			removed = theirs
+		}
+		return putRelationship(tx, &blocked)
+	})
+	if err != nil {
+		respondRelationshipError(c, err)
+		return
+	}
+
+	rh.Hub.DispatchToUsers([]uint{userID}, "relationship_add", toRelationshipResponse(blocked, target))
+	if removed != nil {
+		rh.Hub.DispatchToUsers([]uint{target.ID}, "relationship_remove", RelationshipRemovePayload{ID: userID, Type: removed.Type})
+	}
+	c.JSON(http.StatusOK, toRelationshipResponse(blocked, target))
+}
+
+// DeleteRelationship recusa ou cancela um pedido de amizade, desfaz uma amizade
+// ou desbloqueia o usuário, conforme o relacionamento atual.
+func (rh *RelationshipHandler) DeleteRelationship(c *gin.Context) {
+	rawUserID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+	userID := rawUserID.(uint)
+
+	targetID, ok := parseIDParam(c, "userId")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID do usuário inválido"})
+		return
+	}
+
+	var mine, theirs *models.Relationship
+	err := rh.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		mine, theirs, err = loadRelationshipPair(tx, userID, targetID)
+		if err != nil {
+			return err
+		}
+		if mine == nil {
+			return errRelationshipNotFound
+		}
+		if err := tx.Delete(mine).Error; err != nil {
+			return err
+		}
+		// Desbloquear não mexe no outro lado; amizade e pedidos são desfeitos para os dois
+		if mine.Type == RelationshipBlocked || theirs == nil || theirs.Type == RelationshipBlocked {
+			theirs = nil
+			return nil
+		}
+		return tx.Delete(theirs).Error
+	})
+	if err != nil {
+		respondRelationshipError(c, err)
+		return
+	}
+
+	rh.Hub.DispatchToUsers([]uint{userID}, "relationship_remove", RelationshipRemovePayload{ID: targetID, Type: mine.Type})
+	if theirs != nil {
+		rh.Hub.DispatchToUsers([]uint{targetID}, "relationship_remove", RelationshipRemovePayload{ID: userID, Type: theirs.Type})
+	}
+	c.Status(http.StatusNoContent)
+}
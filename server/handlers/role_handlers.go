@@ -0,0 +1,700 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gustavoverneck/discordia/server/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RoleHandler struct {
+	DB *gorm.DB
+}
+
+func NewRoleHandler(db *gorm.DB) *RoleHandler {
+	return &RoleHandler{DB: db}
+}
+
+type CreateRoleRequest struct {
+	Name        string `json:"name" binding:"required,min=1,max=100"`
+	Color       int    `json:"color" binding:"omitempty,min=0,max=16777215"`
+	Permissions int64  `json:"permissions" binding:"omitempty,min=0"`
+}
+
+type UpdateRoleRequest struct {
+	Name        *string `json:"name" binding:"omitempty,min=1,max=100"`
+	Color       *int    `json:"color" binding:"omitempty,min=0,max=16777215"`
+	Permissions *int64  `json:"permissions" binding:"omitempty,min=0"`
+	Position    *int    `json:"position" binding:"omitempty,min=1"`
+}
+
+type RoleResponse struct {
+	ID          uint   `json:"id"`
+	Name        string `json:"name"`
+	Color       int    `json:"color"`
+	Position    int    `json:"position"`
+	Permissions int64  `json:"permissions"`
+	IsDefault   bool   `json:"isDefault"`
+}
+
+func toRoleResponse(role models.Role) RoleResponse {
+	return RoleResponse{
+		ID:          role.ID,
+		Name:        role.Name,
+		Color:       role.Color,
+		Position:    role.Position,
+		Permissions: role.Permissions,
+		IsDefault:   role.IsDefault,
+	}
+}
+
+// findServerRole busca um cargo garantindo que ele pertence ao servidor.
+func findServerRole(db *gorm.DB, serverID, roleID uint) (*models.Role, error) {
+	var role models.Role
+	if err := db.Where("id = ? AND server_id = ?", roleID, serverID).First(&role).Error; err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+// ListRoles lista os cargos do servidor, do mais alto para o mais baixo.
+func (rh *RoleHandler) ListRoles(c *gin.Context) {
+	rawUserID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+	userID := rawUserID.(uint)
+
+	serverID, ok := parseIDParam(c, "serverId")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID do servidor inválido"})
+		return
+	}
+
+	// loadPermissionContext também garante a existência do @everyone
+	if _, err := loadPermissionContext(rh.DB, userID, serverID); err != nil {
+		respondAuthzError(c, err)
+		return
+	}
+
+	var roles []models.Role
+	if err := rh.DB.Where("server_id = ?", serverID).Order("position DESC").Find(&roles).Error; err != nil {
+		log.Printf("Erro ao listar cargos do servidor %d: %v", serverID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao buscar cargos."})
+		return
+	}
+
+	roleResponses := []RoleResponse{}
+	for _, role := range roles {
+		roleResponses = append(roleResponses, toRoleResponse(role))
+	}
+	c.JSON(http.StatusOK, roleResponses)
+}
+
+// CreateRole cria um cargo logo acima do @everyone. Requer MANAGE_ROLES, e só
+// é possível conceder permissões que o próprio usuário possui.
+func (rh *RoleHandler) CreateRole(c *gin.Context) {
+	rawUserID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+	userID := rawUserID.(uint)
+
+	serverID, ok := parseIDParam(c, "serverId")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID do servidor inválido"})
+		return
+	}
+
+	var req CreateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos: " + err.Error()})
+		return
+	}
+
+	pc, err := authorizeServerPermission(rh.DB, userID, serverID, PermissionManageRoles)
+	if err != nil {
+		respondAuthzError(c, err)
+		return
+	}
+	if req.Permissions&^pc.base != 0 {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Você não pode conceder permissões que não possui."})
+		return
+	}
+
+	role := models.Role{
+		ServerID:    serverID,
+		Name:        req.Name,
+		Color:       req.Color,
+		Permissions: req.Permissions,
+		Position:    1,
+	}
+	err = rh.DB.Transaction(func(tx *gorm.DB) error {
+		// Abre espaço na posição 1 empurrando os demais cargos para cima
+		if err := tx.Model(&models.Role{}).
+			Where("server_id = ? AND is_default = ? AND position >= 1", serverID, false).
+			UpdateColumn("position", gorm.Expr("position + 1")).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&role).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, auditEntry{
+			ServerID:   serverID,
+			ActorID:    userID,
+			Action:     AuditRoleCreate,
+			TargetType: AuditTargetRole,
+			TargetID:   role.ID,
+			Changes:    auditDiff(nil, roleAuditFields(role)),
+			Reason:     auditReason(c),
+		})
+	})
+	if err != nil {
+		log.Printf("Erro ao criar cargo no servidor %d: %v", serverID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao criar cargo."})
+		return
+	}
+
+	c.JSON(http.StatusCreated, toRoleResponse(role))
+}
+
+// UpdateRole altera nome, cor, permissões ou posição de um cargo abaixo do cargo mais alto do usuário.
+func (rh *RoleHandler) UpdateRole(c *gin.Context) {
+	rawUserID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+	userID := rawUserID.(uint)
+
+	serverID, ok := parseIDParam(c, "serverId")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID do servidor inválido"})
+		return
+	}
+	roleID, ok := parseIDParam(c, "roleId")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID do cargo inválido"})
+		return
+	}
+
+	var req UpdateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos: " + err.Error()})
+		return
+	}
+
+	pc, err := authorizeServerPermission(rh.DB, userID, serverID, PermissionManageRoles)
+	if err != nil {
+		respondAuthzError(c, err)
+		return
+	}
+	role, err := findServerRole(rh.DB, serverID, roleID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Cargo não encontrado"})
+			return
+		}
+		log.Printf("Erro ao buscar cargo %d: %v", roleID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar cargo."})
+		return
+	}
+	if !role.IsDefault && !pc.canManageRole(role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Você só pode gerenciar cargos abaixo do seu cargo mais alto."})
+		return
+	}
+	if role.IsDefault && (req.Name != nil || req.Position != nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "O nome e a posição do cargo @everyone não podem ser alterados."})
+		return
+	}
+	if req.Permissions != nil && *req.Permissions&^pc.base != 0 {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Você não pode conceder permissões que não possui."})
+		return
+	}
+	if req.Position != nil && !pc.isOwner() && *req.Position >= pc.highestPosition() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Você não pode mover um cargo para cima do seu cargo mais alto."})
+		return
+	}
+
+	before := roleAuditFields(*role)
+	if req.Name != nil {
+		role.Name = *req.Name
+	}
+	if req.Color != nil {
+		role.Color = *req.Color
+	}
+	if req.Permissions != nil {
+		role.Permissions = *req.Permissions
+	}
+
+	err = rh.DB.Transaction(func(tx *gorm.DB) error {
+		if req.Position != nil && *req.Position != role.Position {
+			var maxPosition int
+			if err := tx.Model(&models.Role{}).Where("server_id = ?", serverID).
+				Select("COALESCE(MAX(position), 0)").Scan(&maxPosition).Error; err != nil {
+				return err
+			}
+			newPosition := *req.Position
+			if newPosition > maxPosition {
+				newPosition = maxPosition
+			}
+			// Desloca os cargos entre a posição antiga e a nova para manter as posições contíguas
+			shift := tx.Model(&models.Role{}).Where("server_id = ? AND is_default = ? AND id <> ?", serverID, false, role.ID)
+			if newPosition > role.Position {
+				shift = shift.Where("position > ? AND position <= ?", role.Position, newPosition).
+					UpdateColumn("position", gorm.Expr("position - 1"))
+			} else {
+				shift = shift.Where("position >= ? AND position < ?", newPosition, role.Position).
+					UpdateColumn("position", gorm.Expr("position + 1"))
+			}
+			if shift.Error != nil {
+				return shift.Error
+			}
+			role.Position = newPosition
+		}
+		if err := tx.Save(role).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, auditEntry{
+			ServerID:   serverID,
+			ActorID:    userID,
+			Action:     AuditRoleUpdate,
+			TargetType: AuditTargetRole,
+			TargetID:   role.ID,
+			Changes:    auditDiff(before, roleAuditFields(*role)),
+			Reason:     auditReason(c),
+		})
+	})
+	if err != nil {
+		log.Printf("Erro ao atualizar cargo %d: %v", roleID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao atualizar cargo."})
+		return
+	}
+
+	c.JSON(http.StatusOK, toRoleResponse(*role))
+}
+
+// DeleteRole remove um cargo, suas atribuições e suas sobrescritas de canal.
+func (rh *RoleHandler) DeleteRole(c *gin.Context) {
+	rawUserID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+	userID := rawUserID.(uint)
+
+	serverID, ok := parseIDParam(c, "serverId")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID do servidor inválido"})
+		return
+	}
+	roleID, ok := parseIDParam(c, "roleId")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID do cargo inválido"})
+		return
+	}
+
+	pc, err := authorizeServerPermission(rh.DB, userID, serverID, PermissionManageRoles)
+	if err != nil {
+		respondAuthzError(c, err)
+		return
+	}
+	role, err := findServerRole(rh.DB, serverID, roleID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Cargo não encontrado"})
+			return
+		}
+		log.Printf("Erro ao buscar cargo %d: %v", roleID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar cargo."})
+		return
+	}
+	if role.IsDefault {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "O cargo @everyone não pode ser removido."})
+		return
+	}
+	if !pc.canManageRole(role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Você só pode gerenciar cargos abaixo do seu cargo mais alto."})
+		return
+	}
+
+	err = rh.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("role_id = ?", role.ID).Delete(&models.MemberRole{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("target_type = ? AND target_id = ?", OverwriteTargetRole, role.ID).
+			Delete(&models.PermissionOverwrite{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(role).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Role{}).
+			Where("server_id = ? AND is_default = ? AND position > ?", serverID, false, role.Position).
+			UpdateColumn("position", gorm.Expr("position - 1")).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, auditEntry{
+			ServerID:   serverID,
+			ActorID:    userID,
+			Action:     AuditRoleDelete,
+			TargetType: AuditTargetRole,
+			TargetID:   role.ID,
+			Changes:    auditDiff(roleAuditFields(*role), nil),
+			Reason:     auditReason(c),
+		})
+	})
+	if err != nil {
+		log.Printf("Erro ao remover cargo %d: %v", roleID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao remover cargo."})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// memberRoleTarget valida os parâmetros comuns de atribuição/remoção de cargo de um membro.
+func (rh *RoleHandler) memberRoleTarget(c *gin.Context) (userID, serverID, targetUserID uint, role *models.Role, ok bool) {
+	rawUserID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+	userID = rawUserID.(uint)
+
+	if serverID, ok = parseIDParam(c, "serverId"); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID do servidor inválido"})
+		return
+	}
+	if targetUserID, ok = parseIDParam(c, "userId"); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID do usuário inválido"})
+		return
+	}
+	roleID, ok := parseIDParam(c, "roleId")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID do cargo inválido"})
+		return
+	}
+	ok = false
+
+	pc, err := authorizeServerPermission(rh.DB, userID, serverID, PermissionManageRoles)
+	if err != nil {
+		respondAuthzError(c, err)
+		return
+	}
+	if role, err = findServerRole(rh.DB, serverID, roleID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Cargo não encontrado"})
+			return
+		}
+		log.Printf("Erro ao buscar cargo %d: %v", roleID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar cargo."})
+		return
+	}
+	if role.IsDefault {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "O cargo @everyone é implícito e não pode ser atribuído."})
+		return
+	}
+	if !pc.canManageRole(role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Você só pode gerenciar cargos abaixo do seu cargo mais alto."})
+		return
+	}
+
+	member, err := isServerMember(rh.DB, targetUserID, serverID)
+	if err != nil {
+		log.Printf("Erro ao verificar membro %d do servidor %d: %v", targetUserID, serverID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao verificar membro."})
+		return
+	}
+	if !member {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Membro não encontrado"})
+		return
+	}
+	return userID, serverID, targetUserID, role, true
+}
+
+// AddMemberRole atribui um cargo a um membro do servidor.
+func (rh *RoleHandler) AddMemberRole(c *gin.Context) {
+	userID, serverID, targetUserID, role, ok := rh.memberRoleTarget(c)
+	if !ok {
+		return
+	}
+
+	memberRole := models.MemberRole{UserID: targetUserID, RoleID: role.ID, ServerID: serverID}
+	if err := rh.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&memberRole)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error // O membro já tinha o cargo
+		}
+		return recordAudit(tx, auditEntry{
+			ServerID:   serverID,
+			ActorID:    userID,
+			Action:     AuditMemberRoleAdd,
+			TargetType: AuditTargetUser,
+			TargetID:   targetUserID,
+			Changes:    []AuditChange{{Key: "role_id", New: role.ID}},
+			Reason:     auditReason(c),
+		})
+	}); err != nil {
+		log.Printf("Erro ao atribuir cargo %d ao usuário %d: %v", role.ID, targetUserID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao atribuir cargo."})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// RemoveMemberRole remove um cargo de um membro do servidor.
+func (rh *RoleHandler) RemoveMemberRole(c *gin.Context) {
+	userID, serverID, targetUserID, role, ok := rh.memberRoleTarget(c)
+	if !ok {
+		return
+	}
+
+	if err := rh.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("user_id = ? AND role_id = ?", targetUserID, role.ID).Delete(&models.MemberRole{})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error // O membro não tinha o cargo
+		}
+		return recordAudit(tx, auditEntry{
+			ServerID:   serverID,
+			ActorID:    userID,
+			Action:     AuditMemberRoleRemove,
+			TargetType: AuditTargetUser,
+			TargetID:   targetUserID,
+			Changes:    []AuditChange{{Key: "role_id", Old: role.ID}},
+			Reason:     auditReason(c),
+		})
+	}); err != nil {
+		log.Printf("Erro ao remover cargo %d do usuário %d: %v", role.ID, targetUserID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao remover cargo."})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+type PermissionOverwriteRequest struct {
+	Allow int64 `json:"allow" binding:"omitempty,min=0"`
+	Deny  int64 `json:"deny" binding:"omitempty,min=0"`
+}
+
+type PermissionOverwriteResponse struct {
+	TargetType string `json:"targetType"`
+	TargetID   uint   `json:"targetId"`
+	Allow      int64  `json:"allow"`
+	Deny       int64  `json:"deny"`
+}
+
+// overwriteTarget valida os parâmetros de rota de uma sobrescrita de canal e
+// garante que o usuário tem MANAGE_ROLES no canal.
+func (rh *RoleHandler) overwriteTarget(c *gin.Context) (userID uint, channel *models.Channel, perms int64, targetType string, targetID uint, ok bool) {
+	rawUserID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+	userID = rawUserID.(uint)
+
+	channelID, ok := parseIDParam(c, "channelId")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID do canal inválido"})
+		return
+	}
+	targetType = c.Param("targetType")
+	if targetType != OverwriteTargetRole && targetType != OverwriteTargetMember {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tipo de alvo inválido (use 'role' ou 'member')"})
+		return
+	}
+	if targetID, ok = parseIDParam(c, "targetId"); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID do alvo inválido"})
+		return
+	}
+	ok = false
+
+	channel, perms, err := authorizeChannel(rh.DB, userID, channelID, PermissionViewChannel|PermissionManageRoles)
+	if err != nil {
+		respondAuthzError(c, err)
+		return
+	}
+	return userID, channel, perms, targetType, targetID, true
+}
+
+// ListChannelPermissions lista as sobrescritas de permissão de um canal.
+func (rh *RoleHandler) ListChannelPermissions(c *gin.Context) {
+	rawUserID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+	userID := rawUserID.(uint)
+
+	channelID, ok := parseIDParam(c, "channelId")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID do canal inválido"})
+		return
+	}
+	if _, _, err := authorizeChannel(rh.DB, userID, channelID, PermissionViewChannel); err != nil {
+		respondAuthzError(c, err)
+		return
+	}
+
+	var overwrites []models.PermissionOverwrite
+	if err := rh.DB.Where("channel_id = ?", channelID).Find(&overwrites).Error; err != nil {
+		log.Printf("Erro ao listar sobrescritas do canal %d: %v", channelID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao buscar permissões do canal."})
+		return
+	}
+
+	overwriteResponses := []PermissionOverwriteResponse{}
+	for _, ow := range overwrites {
+		overwriteResponses = append(overwriteResponses, PermissionOverwriteResponse{
+			TargetType: ow.TargetType,
+			TargetID:   ow.TargetID,
+			Allow:      ow.Allow,
+			Deny:       ow.Deny,
+		})
+	}
+	c.JSON(http.StatusOK, overwriteResponses)
+}
+
+// EditChannelPermission cria ou substitui a sobrescrita de um cargo ou membro em um canal.
+// Só é possível permitir ou negar permissões que o próprio usuário possui no canal.
+func (rh *RoleHandler) EditChannelPermission(c *gin.Context) {
+	userID, channel, perms, targetType, targetID, ok := rh.overwriteTarget(c)
+	if !ok {
+		return
+	}
+
+	var req PermissionOverwriteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos: " + err.Error()})
+		return
+	}
+	if (req.Allow|req.Deny)&^perms != 0 {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Você não pode alterar permissões que não possui neste canal."})
+		return
+	}
+	if req.Allow&req.Deny != 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Uma permissão não pode ser permitida e negada ao mesmo tempo."})
+		return
+	}
+
+	// O alvo precisa pertencer ao servidor do canal. DMs nunca chegam aqui,
+	// pois seus participantes não têm MANAGE_ROLES.
+	serverID := *channel.ServerID
+	switch targetType {
+	case OverwriteTargetRole:
+		if _, err := findServerRole(rh.DB, serverID, targetID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Cargo não encontrado"})
+				return
+			}
+			log.Printf("Erro ao buscar cargo %d: %v", targetID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar cargo."})
+			return
+		}
+	case OverwriteTargetMember:
+		member, err := isServerMember(rh.DB, targetID, serverID)
+		if err != nil {
+			log.Printf("Erro ao verificar membro %d do servidor %d: %v", targetID, serverID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao verificar membro."})
+			return
+		}
+		if !member {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Membro não encontrado"})
+			return
+		}
+	}
+
+	// Em uma categoria, a alteração vale também para os canais sincronizados com ela
+	var overwrite models.PermissionOverwrite
+	err := rh.DB.Transaction(func(tx *gorm.DB) error {
+		before, err := findOverwriteAuditFields(tx, channel.ID, targetType, targetID)
+		if err != nil {
+			return err
+		}
+		channelIDs, err := overwriteChannelIDs(tx, channel)
+		if err != nil {
+			return err
+		}
+		for _, channelID := range channelIDs {
+			overwrite = models.PermissionOverwrite{}
+			err := tx.Where(models.PermissionOverwrite{ChannelID: channelID, TargetType: targetType, TargetID: targetID}).
+				Assign(map[string]interface{}{"allow": req.Allow, "deny": req.Deny}). // Map: zero também é gravado
+				FirstOrCreate(&overwrite).Error
+			if err != nil {
+				return err
+			}
+		}
+		return recordAudit(tx, auditEntry{
+			ServerID:   serverID,
+			ActorID:    userID,
+			Action:     AuditOverwriteUpdate,
+			TargetType: AuditTargetChannel,
+			TargetID:   channel.ID,
+			Changes:    auditDiff(before, overwriteAuditFields(overwrite)),
+			Options:    map[string]interface{}{"type": targetType, "id": targetID},
+			Reason:     auditReason(c),
+		})
+	})
+	if err != nil {
+		log.Printf("Erro ao salvar sobrescrita do canal %d: %v", channel.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao salvar permissões do canal."})
+		return
+	}
+
+	c.JSON(http.StatusOK, PermissionOverwriteResponse{
+		TargetType: overwrite.TargetType,
+		TargetID:   overwrite.TargetID,
+		Allow:      overwrite.Allow,
+		Deny:       overwrite.Deny,
+	})
+}
+
+// DeleteChannelPermission remove a sobrescrita de um cargo ou membro em um canal.
+func (rh *RoleHandler) DeleteChannelPermission(c *gin.Context) {
+	userID, channel, _, targetType, targetID, ok := rh.overwriteTarget(c)
+	if !ok {
+		return
+	}
+
+	err := rh.DB.Transaction(func(tx *gorm.DB) error {
+		before, err := findOverwriteAuditFields(tx, channel.ID, targetType, targetID)
+		if err != nil {
+			return err
+		}
+		channelIDs, err := overwriteChannelIDs(tx, channel)
+		if err != nil {
+			return err
+		}
+		if err := tx.Unscoped().
+			Where("channel_id IN ? AND target_type = ? AND target_id = ?", channelIDs, targetType, targetID).
+			Delete(&models.PermissionOverwrite{}).Error; err != nil {
+			return err
+		}
+		if before == nil {
+			return nil // Não havia sobrescrita a remover
+		}
+		return recordAudit(tx, auditEntry{
+			ServerID:   *channel.ServerID,
+			ActorID:    userID,
+			Action:     AuditOverwriteDelete,
+			TargetType: AuditTargetChannel,
+			TargetID:   channel.ID,
+			Changes:    auditDiff(before, nil),
+			Options:    map[string]interface{}{"type": targetType, "id": targetID},
+			Reason:     auditReason(c),
+		})
+	})
+	if err != nil {
+		log.Printf("Erro ao remover sobrescrita do canal %d: %v", channel.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao remover permissões do canal."})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,413 @@
+package handlers
+
+import (
+	"errors"
+	"html"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gustavoverneck/discordia/server/models"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultSearchLimit = 25
+	maxSearchLimit     = 50
+	maxSearchOffset    = 5000
+	searchSnippetSize  = 16 // Tokens ao redor do trecho encontrado
+	searchDateLayout   = "2006-01-02"
+
+	// Marcadores de destaque dos trechos retornados pela busca. O restante do
+	// trecho tem o HTML escapado, então <mark> é a única marcação possível.
+	searchHighlightStart = "<mark>"
+	searchHighlightEnd   = "</mark>"
+
+	// Caracteres de uso privado passados ao snippet() do FTS5 no lugar dos
+	// marcadores e trocados por eles depois do escape. Uma mensagem que os
+	// contenha no máximo produz um destaque a mais, nunca outra marcação.
+	ftsHighlightStart = "\uE000"
+	ftsHighlightEnd   = "\uE001"
+)
+
+var errInvalidSearch = errors.New("busca inválida")
+
+type SearchHandler struct {
+	DB  *gorm.DB
+	FTS bool // Índice FTS5 disponível; sem ele a busca usa LIKE
+}
+
+func NewSearchHandler(db *gorm.DB, fts bool) *SearchHandler {
+	return &SearchHandler{DB: db, FTS: fts}
+}
+
+// SearchResult é uma mensagem encontrada, com o trecho que casou com a busca.
+type SearchResult struct {
+	MessageResponse
+	Snippet string `json:"snippet"`
+}
+
+type SearchResponse struct {
+	Results []SearchResult `json:"results"` // Mais recentes primeiro
+	Total   int64          `json:"total"`
+}
+
+// searchQuery é a forma estruturada do parâmetro q, ex:
+// `prazo "entrega final" from:ana in:geral after:2024-01-31 mentions:<@3>`.
+type searchQuery struct {
+	Terms         []string // Palavras ou frases (entre aspas) do texto livre
+	From          []string
+	In            []string
+	Mentions      []string
+	HasAttachment bool
+	Before        *time.Time // Mensagens criadas antes do início deste dia
+	After         *time.Time // Mensagens criadas depois do fim deste dia
+}
+
+func (sq *searchQuery) empty() bool {
+	return len(sq.Terms) == 0 && len(sq.From) == 0 && len(sq.In) == 0 && len(sq.Mentions) == 0 &&
+		!sq.HasAttachment && sq.Before == nil && sq.After == nil
+}
+
+// tokenizeSearch separa a busca por espaços, mantendo juntos os trechos entre aspas.
+func tokenizeSearch(q string) []string {
+	var tokens []string
+	var current strings.Builder
+	inQuotes := false
+	for _, r := range q {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			current.WriteRune(r)
+		case (r == ' ' || r == '\t' || r == '\n') && !inQuotes:
+			if current.Len() > 0 {
+				tokens = append(tokens, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if current.Len() > 0 {
+		tokens = append(tokens, current.String())
+	}
+	return tokens
+}
+
+// parseSearchDate aceita datas no formato AAAA-MM-DD, no fuso local do servidor
+// (o mesmo usado por parseTimeQuery).
+func parseSearchDate(value string) (*time.Time, error) {
+	date, err := time.ParseInLocation(searchDateLayout, value, time.Local)
+	if err != nil {
+		return nil, errInvalidSearch
+	}
+	return &date, nil
+}
+
+// parseSearchQuery interpreta os filtros from:, in:, has:, before:, after: e
+// mentions:. Qualquer outro token faz parte do texto livre.
+func parseSearchQuery(q string) (searchQuery, error) {
+	var sq searchQuery
+	for _, token := range tokenizeSearch(q) {
+		key, value, found := strings.Cut(token, ":")
+		value = strings.Trim(value, `"`)
+		if !found || value == "" {
+			if term := strings.Trim(token, `"`); term != "" {
+				sq.Terms = append(sq.Terms, term)
+			}
+			continue
+		}
+
+		var err error
+		switch strings.ToLower(key) {
+		case "from":
+			sq.From = append(sq.From, value)
+		case "in":
+			sq.In = append(sq.In, value)
+		case "mentions":
+			sq.Mentions = append(sq.Mentions, value)
+		case "has":
+			if strings.ToLower(value) != "attachment" {
+				return sq, errInvalidSearch
+			}
+			sq.HasAttachment = true
+		case "before":
+			sq.Before, err = parseSearchDate(value)
+		case "after":
+			sq.After, err = parseSearchDate(value)
+		default:
+			sq.Terms = append(sq.Terms, strings.Trim(token, `"`))
+		}
+		if err != nil {
+			return sq, err
+		}
+	}
+	return sq, nil
+}
+
+// referenceID extrai o ID de referências como "12", "<@12>" ou "<#12>".
+func referenceID(value, prefix string) (uint, bool) {
+	value = strings.TrimSuffix(strings.TrimPrefix(value, prefix), ">")
+	id, err := strconv.ParseUint(value, 10, 32)
+	if err != nil || id == 0 {
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// resolveSearchUsers converte nomes de usuário ou menções em IDs.
+func resolveSearchUsers(db *gorm.DB, values []string) ([]uint, error) {
+	var ids []uint
+	var usernames []string
+	for _, value := range values {
+		if id, ok := referenceID(value, "<@"); ok {
+			ids = append(ids, id)
+		} else {
+			usernames = append(usernames, value)
+		}
+	}
+	if len(usernames) == 0 {
+		return ids, nil
+	}
+
+	var userIDs []uint
+	if err := db.Model(&models.User{}).Where("username IN ?", usernames).Pluck("id", &userIDs).Error; err != nil {
+		return nil, err
+	}
+	return append(ids, userIDs...), nil
+}
+
+// ftsMatchExpression monta a expressão MATCH do FTS5 com cada termo entre
+// aspas, evitando que a sintaxe do FTS5 seja interpretada a partir da entrada.
+func ftsMatchExpression(terms []string) string {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+	}
+	return strings.Join(quoted, " ")
+}
+
+// escapeLike escapa os curingas do LIKE; usado com ESCAPE '\'.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// SearchMessages lida com GET /servers/:serverId/messages/search?q=...
+// Apenas mensagens de canais em que o usuário tem VIEW_CHANNEL e
+// READ_MESSAGE_HISTORY são consideradas.
+func (sh *SearchHandler) SearchMessages(c *gin.Context) {
+	rawUserID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+	userID := rawUserID.(uint)
+
+	serverID, ok := parseIDParam(c, "serverId")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID do servidor inválido"})
+		return
+	}
+
+	sq, err := parseSearchQuery(c.Query("q"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Busca inválida: use has:attachment e datas no formato AAAA-MM-DD."})
+		return
+	}
+	if sq.empty() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Informe um texto ou filtro para a busca."})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultSearchLimit)))
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Parâmetro limit inválido"})
+		return
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 || offset > maxSearchOffset {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Parâmetro offset inválido"})
+		return
+	}
+
+	pc, err := loadPermissionContext(sh.DB, userID, serverID)
+	if err != nil {
+		respondAuthzError(c, err)
+		return
+	}
+	channels, perms, err := pc.serverChannelPermissions(sh.DB)
+	if err != nil {
+		log.Printf("Erro ao calcular permissões dos canais do servidor %d: %v", serverID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao buscar mensagens."})
+		return
+	}
+
+	// Canais pesquisáveis: legíveis pelo usuário e, se houver in:, citados no filtro
+	required := PermissionViewChannel | PermissionReadMessageHistory
+	var channelIDs []uint
+	for _, channel := range channels {
+		if perms[channel.ID]&required != required || !matchesChannelFilter(channel, sq.In) {
+			continue
+		}
+		channelIDs = append(channelIDs, channel.ID)
+	}
+
+	response := SearchResponse{Results: []SearchResult{}}
+	if len(channelIDs) == 0 {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	query := sh.DB.Model(&models.Message{}).Where("messages.channel_id IN ?", channelIDs)
+	if len(sq.From) > 0 {
+		fromIDs, err := resolveSearchUsers(sh.DB, sq.From)
+		if err != nil {
+			log.Printf("Erro ao resolver usuários da busca no servidor %d: %v", serverID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao buscar mensagens."})
+			return
+		}
+		query = query.Where("messages.author_id IN ?", fromIDs)
+	}
+	if len(sq.Mentions) > 0 {
+		mentionIDs, err := resolveSearchUsers(sh.DB, sq.Mentions)
+		if err != nil {
+			log.Printf("Erro ao resolver usuários da busca no servidor %d: %v", serverID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao buscar mensagens."})
+			return
+		}
+		query = query.Where("EXISTS (SELECT 1 FROM message_mentions WHERE message_mentions.message_id = messages.id AND message_mentions.user_id IN ?)", mentionIDs)
+	}
+	if sq.HasAttachment {
+		query = query.Where("EXISTS (SELECT 1 FROM attachments WHERE attachments.message_id = messages.id AND attachments.deleted_at IS NULL)")
+	}
+	if sq.Before != nil {
+		query = query.Where("messages.created_at < ?", *sq.Before)
+	}
+	if sq.After != nil {
+		query = query.Where("messages.created_at >= ?", sq.After.AddDate(0, 0, 1))
+	}
+
+	snippetColumn := "''"
+	if len(sq.Terms) > 0 {
+		if sh.FTS {
+			query = query.Joins("JOIN messages_fts ON messages_fts.rowid = messages.id").
+				Where("messages_fts MATCH ?", ftsMatchExpression(sq.Terms))
+			snippetColumn = "snippet(messages_fts, 0, '" + ftsHighlightStart + "', '" + ftsHighlightEnd + "', '…', " +
+				strconv.Itoa(searchSnippetSize) + ")"
+		} else {
+			for _, term := range sq.Terms {
+				query = query.Where(`messages.content LIKE ? ESCAPE '\'`, "%"+escapeLike(term)+"%")
+			}
+		}
+	}
+
+	if err := query.Session(&gorm.Session{}).Count(&response.Total).Error; err != nil {
+		log.Printf("Erro ao contar resultados da busca no servidor %d: %v", serverID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao buscar mensagens."})
+		return
+	}
+
+	var hits []struct {
+		ID      uint
+		Snippet string
+	}
+	err = query.Select("messages.id AS id, " + snippetColumn + " AS snippet").
+		Order("messages.id DESC").Limit(limit).Offset(offset).
+		Scan(&hits).Error
+	if err != nil {
+		log.Printf("Erro ao buscar mensagens no servidor %d: %v", serverID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao buscar mensagens."})
+		return
+	}
+	if len(hits) == 0 {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	ids := make([]uint, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ID
+	}
+	var messages []models.Message
+	if err := sh.DB.Scopes(withMessageRelations).Where("id IN ?", ids).Order("id DESC").Find(&messages).Error; err != nil {
+		log.Printf("Erro ao carregar mensagens da busca no servidor %d: %v", serverID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao buscar mensagens."})
+		return
+	}
+	messageResponses, err := buildMessageResponses(sh.DB, messages)
+	if err != nil {
+		log.Printf("Erro ao contar respostas da busca no servidor %d: %v", serverID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao buscar mensagens."})
+		return
+	}
+
+	snippets := make(map[uint]string, len(hits))
+	for _, hit := range hits {
+		snippets[hit.ID] = hit.Snippet
+	}
+	for _, message := range messageResponses {
+		snippet := snippets[message.ID]
+		if snippet == "" {
+			snippet = truncateRunes(message.Content, messagePreviewLength)
+		}
+		snippet = highlightSnippet(snippet)
+		response.Results = append(response.Results, SearchResult{MessageResponse: message, Snippet: snippet})
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// highlightSnippet escapa o HTML do trecho e troca os marcadores do FTS5 por
+// <mark>, sempre em pares: um fechamento sem abertura é descartado e um
+// destaque aberto é fechado no fim do trecho.
+func highlightSnippet(snippet string) string {
+	var b strings.Builder
+	open := false
+	for snippet != "" {
+		i := strings.IndexAny(snippet, ftsHighlightStart+ftsHighlightEnd)
+		if i < 0 {
+			b.WriteString(html.EscapeString(snippet))
+			break
+		}
+		b.WriteString(html.EscapeString(snippet[:i]))
+		marker := snippet[i:]
+		if strings.HasPrefix(marker, ftsHighlightStart) {
+			if !open {
+				b.WriteString(searchHighlightStart)
+				open = true
+			}
+			snippet = marker[len(ftsHighlightStart):]
+		} else {
+			if open {
+				b.WriteString(searchHighlightEnd)
+				open = false
+			}
+			snippet = marker[len(ftsHighlightEnd):]
+		}
+	}
+	if open {
+		b.WriteString(searchHighlightEnd)
+	}
+	return b.String()
+}
+
+// matchesChannelFilter informa se o canal foi citado em algum in: (por ID, <#id> ou nome).
+// Sem filtro, todos os canais casam.
+func matchesChannelFilter(channel models.Channel, filters []string) bool {
+	if len(filters) == 0 {
+		return true
+	}
+	for _, filter := range filters {
+		if id, ok := referenceID(filter, "<#"); ok && id == channel.ID {
+			return true
+		}
+		if strings.EqualFold(strings.TrimPrefix(filter, "#"), channel.ChannelName) {
+			return true
+		}
+	}
+	return false
+}
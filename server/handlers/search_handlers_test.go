@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func searchDate(value string) *time.Time {
+	date, _ := time.ParseInLocation(searchDateLayout, value, time.Local)
+	return &date
+}
+
+func TestParseSearchQuery(t *testing.T) {
+	tests := []struct {
+		q       string
+		want    searchQuery
+		wantErr bool
+	}{
+		{"", searchQuery{}, false},
+		{"prazo entrega", searchQuery{Terms: []string{"prazo", "entrega"}}, false},
+		{`prazo "entrega final"`, searchQuery{Terms: []string{"prazo", "entrega final"}}, false},
+		{`"aspas sem fim`, searchQuery{Terms: []string{"aspas sem fim"}}, false},
+		{`""`, searchQuery{}, false},
+		{"from:ana from:<@3>", searchQuery{From: []string{"ana", "<@3>"}}, false},
+		{`from:"ana"`, searchQuery{From: []string{"ana"}}, false},
+		{"in:geral in:<#7>", searchQuery{In: []string{"geral", "<#7>"}}, false},
+		{"mentions:<@3> olá", searchQuery{Terms: []string{"olá"}, Mentions: []string{"<@3>"}}, false},
+		{"FROM:ana In:geral", searchQuery{From: []string{"ana"}, In: []string{"geral"}}, false},
+		{"has:attachment", searchQuery{HasAttachment: true}, false},
+		{"has:Attachment", searchQuery{HasAttachment: true}, false},
+		{"has:link", searchQuery{}, true},
+		{"before:2024-01-31 after:2023-12-01",
+			searchQuery{Before: searchDate("2024-01-31"), After: searchDate("2023-12-01")}, false},
+		{"before:2024-02-30", searchQuery{}, true},
+		{"after:31/01/2024", searchQuery{}, true},
+		{"before:ontem", searchQuery{}, true},
+		// Filtros desconhecidos ou vazios são texto livre
+		{"http://exemplo.com", searchQuery{Terms: []string{"http://exemplo.com"}}, false},
+		{"from:", searchQuery{Terms: []string{"from:"}}, false},
+		{`in:"" prazo`, searchQuery{Terms: []string{"in:", "prazo"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.q, func(t *testing.T) {
+			got, err := parseSearchQuery(tt.q)
+			if tt.wantErr {
+				if !errors.Is(err, errInvalidSearch) {
+					t.Fatalf("erro = %v, esperado errInvalidSearch", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("erro inesperado: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseSearchQuery = %+v, esperado %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSearchDateLocal(t *testing.T) {
+	// before: e after: usam o fuso local, como parseTimeQuery.
+	date, err := parseSearchDate("2024-01-31")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := time.Date(2024, 1, 31, 0, 0, 0, 0, time.Local)
+	if !date.Equal(want) || date.Location() != time.Local {
+		t.Errorf("parseSearchDate = %v, esperado %v", date, want)
+	}
+}
+
+func TestFTSMatchExpression(t *testing.T) {
+	tests := []struct {
+		name  string
+		terms []string
+		want  string
+	}{
+		{"termo simples", []string{"prazo"}, `"prazo"`},
+		{"vários termos", []string{"prazo", "entrega final"}, `"prazo" "entrega final"`},
+		{"aspas duplicadas", []string{`diz "oi"`}, `"diz ""oi"""`},
+		{"asterisco é literal", []string{"pref*"}, `"pref*"`},
+		{"NEAR é literal", []string{"NEAR(a b)"}, `"NEAR(a b)"`},
+		{"operadores são literais", []string{"a", "OR", "NOT", "b"}, `"a" "OR" "NOT" "b"`},
+		{"coluna não é interpretada", []string{"content:x"}, `"content:x"`},
+		{"sem termos", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ftsMatchExpression(tt.terms); got != tt.want {
+				t.Errorf("ftsMatchExpression = %s, esperado %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"prazo", "prazo"},
+		{"100%", `100\%`},
+		{"nome_arquivo", `nome\_arquivo`},
+		{`C:\temp`, `C:\\temp`},
+		{`\%_`, `\\\%\_`},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := escapeLike(tt.in); got != tt.want {
+				t.Errorf("escapeLike(%q) = %q, esperado %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHighlightSnippet(t *testing.T) {
+	tests := []struct {
+		name, snippet, want string
+	}{
+		{"sem destaque", "texto comum", "texto comum"},
+		{"destaque", "o " + ftsHighlightStart + "prazo" + ftsHighlightEnd + " acabou",
+			"o <mark>prazo</mark> acabou"},
+		{"HTML é escapado", `<script>alert("x")</script> & ` + ftsHighlightStart + "<b>" + ftsHighlightEnd,
+			"&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt; &amp; <mark>&lt;b&gt;</mark>"},
+		{"<mark> no conteúdo é escapado", "<mark>falso</mark>", "&lt;mark&gt;falso&lt;/mark&gt;"},
+		{"fechamento sem abertura é descartado", "a" + ftsHighlightEnd + "b", "ab"},
+		{"abertura sem fechamento é fechada", ftsHighlightStart + "a", "<mark>a</mark>"},
+		{"abertura repetida é ignorada", ftsHighlightStart + "a" + ftsHighlightStart + "b" + ftsHighlightEnd,
+			"<mark>ab</mark>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := highlightSnippet(tt.snippet); got != tt.want {
+				t.Errorf("highlightSnippet = %q, esperado %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,413 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gustavoverneck/discordia/server/models"
+	"github.com/gustavoverneck/discordia/server/storage"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// errOwnerChanged indica que o servidor mudou de dono durante a transferência.
+var errOwnerChanged = errors.New("o servidor já foi transferido")
+
+// ServerHandler gerencia as configurações de um servidor já criado.
+type ServerHandler struct {
+	DB      *gorm.DB
+	Hub     *Hub
+	Storage storage.Storage // Onde os ícones dos servidores são gravados
+}
+
+func NewServerHandler(db *gorm.DB, hub *Hub, store storage.Storage) *ServerHandler {
+	return &ServerHandler{DB: db, Hub: hub, Storage: store}
+}
+
+// UpdateServerRequest é o corpo de PATCH /servers/:serverId. Para trocar o
+// ícone, use multipart/form-data com os mesmos campos e a imagem em "iconFile".
+type UpdateServerRequest struct {
+	Name        *string `json:"name" form:"name" binding:"omitempty,max=100"`
+	Description *string `json:"description" form:"description" binding:"omitempty,max=1024"`
+	RemoveIcon  bool    `json:"removeIcon" form:"removeIcon"` // Remove o ícone atual
+}
+
+type TransferOwnershipRequest struct {
+	UserID   uint   `json:"userId" binding:"required"`
+	Password string `json:"password" binding:"required"` // Senha do dono atual, como confirmação
+}
+
+// ServerResponse é o corpo das respostas de servidor e do evento server_update.
+type ServerResponse struct {
+	ID          uint      `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	IconURL     string    `json:"iconUrl,omitempty"`
+	OwnerID     uint      `json:"ownerId"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+type ServerDeletePayload struct {
+	ID uint `json:"id"`
+}
+
+func toServerResponse(server models.Server) ServerResponse {
+	return ServerResponse{
+		ID:          server.ID,
+		Name:        server.ServerName,
+		Description: server.Description,
+		IconURL:     server.IconURL,
+		OwnerID:     server.OwnerID,
+		CreatedAt:   server.CreatedAt,
+	}
+}
+
+// serverMemberIDs lista os IDs de todos os membros do servidor.
+func serverMemberIDs(db *gorm.DB, serverID uint) ([]uint, error) {
+	var ids []uint
+	err := db.Table("server_members").Where("server_id = ?", serverID).Pluck("user_id", &ids).Error
+	return ids, err
+}
+
+// dispatchToMembers envia um evento a todos os membros do servidor.
+func (sh *ServerHandler) dispatchToMembers(serverID uint, eventType string, v interface{}) {
+	memberIDs, err := serverMemberIDs(sh.DB, serverID)
+	if err != nil {
+		log.Printf("Erro ao buscar membros do servidor %d para o evento %s: %v", serverID, eventType, err)
+		return
+	}
+	sh.Hub.DispatchToUsers(memberIDs, eventType, v)
+}
+
+// UpdateServer lida com PATCH /servers/:serverId: nome, descrição e ícone.
+// Requer MANAGE_SERVER. O ícone substituído é apagado do armazenamento.
+func (sh *ServerHandler) UpdateServer(c *gin.Context) {
+	rawUserID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+	userID := rawUserID.(uint)
+
+	serverID, ok := parseIDParam(c, "serverId")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID do servidor inválido"})
+		return
+	}
+
+	pc, err := authorizeServerPermission(sh.DB, userID, serverID, PermissionManageServer)
+	if err != nil {
+		respondAuthzError(c, err)
+		return
+	}
+	server := pc.server
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImageUploadSize+1024*1024)
+	var req UpdateServerRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos: " + err.Error()})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Nome do servidor é obrigatório"})
+			return
+		}
+		updates["server_name"] = name
+	}
+	if req.Description != nil {
+		updates["description"] = strings.TrimSpace(*req.Description)
+	}
+
+	newIconURL := ""
+	fileHeader, err := c.FormFile("iconFile")
+	switch {
+	case err == nil:
+		newIconURL, err = storeImage(c.Request.Context(), sh.Storage, serverIconImage, fileHeader)
+		if err != nil {
+			if message, ok := imageErrorMessage(err); ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": message})
+				return
+			}
+			log.Printf("Erro ao armazenar ícone do servidor %d: %v", serverID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao armazenar o ícone do servidor."})
+			return
+		}
+		updates["icon_url"] = newIconURL
+	case errors.Is(err, http.ErrMissingFile), errors.Is(err, http.ErrNotMultipart):
+		if req.RemoveIcon {
+			updates["icon_url"] = ""
+		}
+	default:
+		log.Printf("Erro ao processar FormFile 'iconFile': %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Erro ao processar o arquivo de ícone."})
+		return
+	}
+
+	if len(updates) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Nenhuma alteração informada."})
+		return
+	}
+
+	before := serverAuditFields(server)
+	oldIconURL := server.IconURL
+	if err := sh.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&server).Updates(updates).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, auditEntry{
+			ServerID:   server.ID,
+			ActorID:    userID,
+			Action:     AuditServerUpdate,
+			TargetType: AuditTargetServer,
+			TargetID:   server.ID,
+			Changes:    auditDiff(before, serverAuditFields(server)),
+			Reason:     auditReason(c),
+		})
+	}); err != nil {
+		deleteImage(c.Request.Context(), sh.Storage, newIconURL)
+		log.Printf("Erro ao atualizar servidor %d: %v", serverID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao atualizar o servidor."})
+		return
+	}
+	if iconURL, changed := updates["icon_url"]; changed && oldIconURL != "" && oldIconURL != iconURL {
+		deleteImage(c.Request.Context(), sh.Storage, oldIconURL)
+	}
+
+	response := toServerResponse(server)
+	sh.dispatchToMembers(server.ID, "server_update", response)
+	c.JSON(http.StatusOK, response)
+}
+
+// DeleteServer lida com DELETE /servers/:serverId. Apenas o dono pode apagar o
+// servidor; canais, mensagens, convites, banimentos, registro de auditoria,
+// cargos e membros são removidos na mesma transação.
+func (sh *ServerHandler) DeleteServer(c *gin.Context) {
+	rawUserID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+	userID := rawUserID.(uint)
+
+	serverID, ok := parseIDParam(c, "serverId")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID do servidor inválido"})
+		return
+	}
+
+	pc, err := loadPermissionContext(sh.DB, userID, serverID)
+	if err != nil {
+		respondAuthzError(c, err)
+		return
+	}
+	if !pc.isOwner() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Apenas o dono pode apagar o servidor."})
+		return
+	}
+	server := pc.server
+
+	// Os destinatários do evento precisam ser lidos antes de as associações serem apagadas
+	memberIDs, err := serverMemberIDs(sh.DB, serverID)
+	if err != nil {
+		log.Printf("Erro ao buscar membros do servidor %d: %v", serverID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao apagar o servidor."})
+		return
+	}
+
+	var channelIDs []uint
+	var memberAvatarURLs []string
+	var attachments []models.Attachment
+	err = sh.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Channel{}).Where("server_id = ?", serverID).Pluck("id", &channelIDs).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.ServerMember{}).Where("server_id = ? AND avatar_url <> ''", serverID).
+			Pluck("avatar_url", &memberAvatarURLs).Error; err != nil {
+			return err
+		}
+		deleted, err := deleteChannelContents(tx, channelIDs)
+		if err != nil {
+			return err
+		}
+		attachments = deleted
+		if err := tx.Where("server_id = ?", serverID).Delete(&models.Channel{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("server_id = ?", serverID).Delete(&models.Invite{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("server_id = ?", serverID).Delete(&models.Ban{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("server_id = ?", serverID).Delete(&models.AuditLogEntry{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("server_id = ?", serverID).Delete(&models.MemberRole{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("server_id = ?", serverID).Delete(&models.Role{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("server_id = ?", serverID).Delete(&models.ServerMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&server).Error
+	})
+	if err != nil {
+		log.Printf("Erro ao apagar servidor %d: %v", serverID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao apagar o servidor."})
+		return
+	}
+	deleteImage(c.Request.Context(), sh.Storage, server.IconURL)
+	for _, avatarURL := range memberAvatarURLs {
+		deleteImage(c.Request.Context(), sh.Storage, avatarURL)
+	}
+	removeAttachmentFiles(c.Request.Context(), sh.Storage, attachments)
+
+	for _, memberID := range memberIDs {
+		for _, channelID := range channelIDs {
+			sh.Hub.UnsubscribeUser(memberID, channelID)
+		}
+	}
+	sh.Hub.DispatchToUsers(memberIDs, "server_delete", ServerDeletePayload{ID: serverID})
+	c.Status(http.StatusNoContent)
+}
+
+// deleteChannelContents apaga as mensagens (com anexos, edições e menções),
+// os estados de leitura e as sobrescritas de permissão dos canais. Retorna os
+// anexos apagados, cujos arquivos devem ser removidos com removeAttachmentFiles
+// depois que a transação for confirmada.
+func deleteChannelContents(tx *gorm.DB, channelIDs []uint) ([]models.Attachment, error) {
+	if len(channelIDs) == 0 {
+		return nil, nil
+	}
+	// Inclui as mensagens já apagadas, cujos anexos ficariam órfãos com o canal
+	messageIDs := tx.Unscoped().Model(&models.Message{}).Select("id").Where("channel_id IN ?", channelIDs)
+	var attachments []models.Attachment
+	if err := tx.Where("message_id IN (?)", messageIDs).Find(&attachments).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("message_id IN (?)", messageIDs).Delete(&models.Attachment{}).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("message_id IN (?)", messageIDs).Delete(&models.MessageEdit{}).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("message_id IN (?)", messageIDs).Delete(&models.MessageMention{}).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("channel_id IN ?", channelIDs).Delete(&models.Message{}).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("channel_id IN ?", channelIDs).Delete(&models.ReadState{}).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("channel_id IN ?", channelIDs).Delete(&models.PermissionOverwrite{}).Error; err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+// TransferOwnership lida com PUT /servers/:serverId/owner. Apenas o dono pode
+// transferir o servidor, confirmando com a própria senha, para outro membro.
+func (sh *ServerHandler) TransferOwnership(c *gin.Context) {
+	rawUserID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+	userID := rawUserID.(uint)
+
+	serverID, ok := parseIDParam(c, "serverId")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID do servidor inválido"})
+		return
+	}
+
+	var req TransferOwnershipRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos: " + err.Error()})
+		return
+	}
+
+	pc, err := loadPermissionContext(sh.DB, userID, serverID)
+	if err != nil {
+		respondAuthzError(c, err)
+		return
+	}
+	if !pc.isOwner() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Apenas o dono pode transferir o servidor."})
+		return
+	}
+	server := pc.server
+
+	var owner models.User
+	if err := sh.DB.First(&owner, userID).Error; err != nil {
+		log.Printf("Erro ao buscar usuário %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao transferir o servidor."})
+		return
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(owner.PasswordHash), []byte(req.Password)); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Senha incorreta."})
+		return
+	}
+
+	if req.UserID == userID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Você já é o dono deste servidor."})
+		return
+	}
+	member, err := isServerMember(sh.DB, req.UserID, serverID)
+	if err != nil {
+		log.Printf("Erro ao verificar membro %d do servidor %d: %v", req.UserID, serverID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao transferir o servidor."})
+		return
+	}
+	if !member {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "O novo dono precisa ser membro do servidor."})
+		return
+	}
+
+	err = sh.DB.Transaction(func(tx *gorm.DB) error {
+		// Condicional ao dono atual, para que duas transferências simultâneas não se sobreponham
+		result := tx.Model(&models.Server{}).
+			Where("id = ? AND owner_id = ?", serverID, userID).
+			Update("owner_id", req.UserID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errOwnerChanged
+		}
+		return recordAudit(tx, auditEntry{
+			ServerID:   serverID,
+			ActorID:    userID,
+			Action:     AuditServerOwnerTransfer,
+			TargetType: AuditTargetUser,
+			TargetID:   req.UserID,
+			Changes:    []AuditChange{{Key: "owner_id", Old: userID, New: req.UserID}},
+			Reason:     auditReason(c),
+		})
+	})
+	if errors.Is(err, errOwnerChanged) {
+		c.JSON(http.StatusConflict, gin.H{"error": "O servidor já foi transferido."})
+		return
+	}
+	if err != nil {
+		log.Printf("Erro ao transferir servidor %d para o usuário %d: %v", serverID, req.UserID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao transferir o servidor."})
+		return
+	}
+	server.OwnerID = req.UserID
+
+	response := toServerResponse(server)
+	sh.dispatchToMembers(server.ID, "server_update", response)
+	c.JSON(http.StatusOK, response)
+}
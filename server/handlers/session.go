@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// bufferedEvent é um DISPATCH já serializado, guardado para replay em RESUME.
+type bufferedEvent struct {
+	seq  uint64
+	data []byte
+}
+
+// Session é uma sessão do gateway. Ela sobrevive à queda da conexão por
+// sessionResumeWindow, mantendo suas inscrições e os últimos eventos enviados,
+// para que o cliente possa retomá-la com RESUME sem perder mensagens.
+// Todos os campos, exceto ID e userID, pertencem à goroutine do Hub.
+type Session struct {
+	ID     string
+	userID uint
+
+	client     *Client // Conexão atual; nil enquanto desconectada
+	detachedAt time.Time
+
+	seq      uint64
+	buffer   []bufferedEvent
+	channels map[uint]bool
+
+	lastActivity time.Time // Último comando recebido; usado para detectar ausência
+	afk          bool      // O cliente informou que o usuário está ausente
+}
+
+func newSession(userID uint) *Session {
+	return &Session{
+		ID:       uuid.New().String(),
+		userID:   userID,
+		channels: make(map[uint]bool),
+	}
+}
+
+// nextFrame atribui o próximo número de sequência ao evento, guarda-o no
+// buffer de replay e retorna o frame serializado.
+func (s *Session) nextFrame(eventType string, d interface{}) []byte {
+	s.seq++
+	data := encodeGatewayFrame(OpDispatch, eventType, s.seq, d)
+	if data == nil {
+		return nil
+	}
+	s.buffer = append(s.buffer, bufferedEvent{seq: s.seq, data: data})
+	if len(s.buffer) > sessionBufferSize {
+		s.buffer = s.buffer[len(s.buffer)-sessionBufferSize:]
+	}
+	return data
+}
+
+// eventsAfter retorna os eventos com sequência maior que seq.
+// ok é false quando algum evento necessário já saiu do buffer.
+func (s *Session) eventsAfter(seq uint64) (events []bufferedEvent, ok bool) {
+	if seq > s.seq {
+		return nil, false
+	}
+	if seq == s.seq {
+		return nil, true
+	}
+	if len(s.buffer) == 0 || s.buffer[0].seq > seq+1 {
+		return nil, false
+	}
+	for _, ev := range s.buffer {
+		if ev.seq > seq {
+			events = append(events, ev)
+		}
+	}
+	return events, true
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+)
+
+const (
+	// typingTimeout é por quanto tempo um indicador de digitação vale sem ser renovado.
+	typingTimeout = 10 * time.Second
+	// typingRateLimit é o intervalo mínimo entre dois eventos typing do mesmo usuário no mesmo canal.
+	typingRateLimit = 5 * time.Second
+	// typingSweepInterval é a frequência com que indicadores expirados são removidos.
+	typingSweepInterval = time.Second
+)
+
+// TypingStartPayload é o corpo do comando typing_start.
+type TypingStartPayload struct {
+	ChannelID uint `json:"channelId"`
+}
+
+// TypingPayload é o corpo do evento typing.
+type TypingPayload struct {
+	ChannelID uint      `json:"channelId"`
+	UserID    uint      `json:"userId"`
+	Timestamp time.Time `json:"timestamp"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// TypingStopPayload é o corpo do evento typing_stop, enviado quando o indicador
+// expira ou quando a mensagem do usuário chega ao canal.
+type TypingStopPayload struct {
+	ChannelID uint `json:"channelId"`
+	UserID    uint `json:"userId"`
+}
+
+// typingKey identifica um usuário digitando em um canal.
+type typingKey struct {
+	userID    uint
+	channelID uint
+}
+
+// typingState guarda quando o último evento typing foi enviado e quando ele expira.
+type typingState struct {
+	sentAt    time.Time
+	expiresAt time.Time
+}
+
+// handleTypingStart registra que o usuário está digitando e avisa os demais
+// inscritos no canal, respeitando typingRateLimit. Um evento dentro do limite
+// não é retransmitido, mas ainda renova a expiração do indicador.
+func (h *Hub) handleTypingStart(key typingKey, now time.Time) {
+	if state, ok := h.typing[key]; ok && now.Sub(state.sentAt) < typingRateLimit {
+		state.expiresAt = now.Add(typingTimeout)
+		h.typing[key] = state
+		return
+	}
+	state := typingState{sentAt: now, expiresAt: now.Add(typingTimeout)}
+	h.typing[key] = state
+	h.broadcastTyping(key, "typing", TypingPayload{
+		ChannelID: key.channelID,
+		UserID:    key.userID,
+		Timestamp: now,
+		ExpiresAt: state.expiresAt,
+	})
+}
+
+// handleTypingStop remove o indicador do usuário, se existir.
+func (h *Hub) handleTypingStop(key typingKey) {
+	if _, ok := h.typing[key]; !ok {
+		return
+	}
+	delete(h.typing, key)
+	h.broadcastTyping(key, "typing_stop", TypingStopPayload{ChannelID: key.channelID, UserID: key.userID})
+}
+
+// expireTyping remove os indicadores que não foram renovados a tempo.
+func (h *Hub) expireTyping(now time.Time) {
+	for key, state := range h.typing {
+		if !now.Before(state.expiresAt) {
+			h.handleTypingStop(key)
+		}
+	}
+}
+
+// broadcastTyping envia o evento para os inscritos no canal, exceto as sessões
+// do próprio usuário que está digitando.
+func (h *Hub) broadcastTyping(key typingKey, eventType string, v interface{}) {
+	d, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("Erro ao serializar evento %s para o canal %d: %v", eventType, key.channelID, err)
+		return
+	}
+	for session := range h.channels[key.channelID] {
+		if session.userID != key.userID {
+			h.dispatch(session, eventType, json.RawMessage(d))
+		}
+	}
+}
+
+// StartTyping indica que o usuário começou a digitar no canal.
+func (h *Hub) StartTyping(userID, channelID uint) {
+	h.typingStart <- typingKey{userID: userID, channelID: channelID}
+}
+
+// StopTyping remove o indicador de digitação do usuário no canal, ex: quando sua mensagem é enviada.
+func (h *Hub) StopTyping(userID, channelID uint) {
+	h.typingStop <- typingKey{userID: userID, channelID: channelID}
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/gustavoverneck/discordia/server/storage"
+)
+
+// uploadsURLPrefix é o prefixo das URLs gravadas no banco para arquivos enviados.
+// A URL guardada é estável (/static/<chave>); UploadHandler decide como o
+// arquivo é entregue: servido do disco ou por redirecionamento.
+const uploadsURLPrefix = "/static/"
+
+// storeUpload grava o arquivo no backend como <prefix>/<uuid><ext> e devolve a
+// URL a ser salva no banco.
+func storeUpload(ctx context.Context, store storage.Storage, prefix, ext string, body io.Reader, size int64, contentType string) (string, error) {
+	key := prefix + "/" + uuid.New().String() + ext
+	if err := store.Put(ctx, key, body, size, contentType); err != nil {
+		return "", err
+	}
+	return uploadsURLPrefix + key, nil
+}
+
+// deleteUpload remove do backend o arquivo de uma URL gravada por storeUpload.
+// URLs vazias ou externas são ignoradas.
+func deleteUpload(ctx context.Context, store storage.Storage, url string) {
+	key, ok := strings.CutPrefix(url, uploadsURLPrefix)
+	if !ok || key == "" {
+		return
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		log.Printf("Erro ao remover arquivo %s: %v", key, err)
+	}
+}
+
+// UploadHandler entrega os arquivos enviados (anexos, ícones, avatares).
+type UploadHandler struct {
+	Storage storage.Storage
+}
+
+// NewUploadHandler é o construtor para UploadHandler.
+func NewUploadHandler(store storage.Storage) *UploadHandler {
+	return &UploadHandler{Storage: store}
+}
+
+// ServeUpload lida com GET /static/*key. Imagens com variantes aceitam
+// ?size=N para receber a versão mais adequada àquele tamanho. No disco local o
+// arquivo é servido diretamente; nos demais backends a resposta redireciona
+// para a URL gerada pelo backend (pública ou pré-assinada).
+func (uh *UploadHandler) ServeUpload(c *gin.Context) {
+	originalKey := strings.TrimPrefix(c.Param("key"), "/")
+	key := originalKey
+	if rawSize := c.Query("size"); rawSize != "" {
+		size, err := strconv.Atoi(rawSize)
+		if err != nil || size < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parâmetro size inválido"})
+			return
+		}
+		key = imageVariantKey(key, size)
+	}
+
+	if local, ok := uh.Storage.(*storage.Local); ok {
+		filePath, ok := localUploadPath(local, key)
+		if !ok && key != originalKey {
+			// Ícones enviados antes das variantes existirem só têm o arquivo original
+			filePath, ok = localUploadPath(local, originalKey)
+		}
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Arquivo não encontrado"})
+			return
+		}
+		// Os nomes são UUIDs: o conteúdo de uma URL nunca muda
+		c.Header("Cache-Control", "public, max-age=31536000, immutable")
+		c.Header("X-Content-Type-Options", "nosniff")
+		c.File(filePath)
+		return
+	}
+
+	url, err := uh.Storage.URL(c.Request.Context(), key)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Arquivo não encontrado"})
+		return
+	}
+	// Links pré-assinados expiram: o redirecionamento não pode ficar em cache por muito tempo
+	c.Header("Cache-Control", "private, max-age=60")
+	c.Redirect(http.StatusFound, url)
+}
+
+// localUploadPath devolve o caminho do arquivo no disco, se ele existir.
+func localUploadPath(local *storage.Local, key string) (string, bool) {
+	filePath, err := local.Path(key)
+	if err != nil {
+		return "", false
+	}
+	if info, err := os.Stat(filePath); err != nil || info.IsDir() {
+		return "", false
+	}
+	return filePath, true
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// wsTicketTTL é a validade de um ticket de conexão WebSocket.
+	wsTicketTTL = 30 * time.Second
+	// wsBearerProtocol é o subprotocolo usado para enviar o JWT via Sec-WebSocket-Protocol:
+	// o cliente envia "discordia.bearer, <token>" e o servidor responde com "discordia.bearer".
+	wsBearerProtocol = "discordia.bearer"
+)
+
+var errWSUnauthenticated = errors.New("credenciais ausentes para a conexão WebSocket")
+
+type wsTicket struct {
+	userID    uint
+	username  string
+	expiresAt time.Time
+}
+
+// TicketStore guarda tickets de uso único para autenticar upgrades WebSocket
+// em clientes que não conseguem enviar cabeçalhos (ex: WebSocket do navegador).
+type TicketStore struct {
+	mu      sync.Mutex
+	tickets map[string]wsTicket
+}
+
+// NewTicketStore é o construtor para TicketStore.
+func NewTicketStore() *TicketStore {
+	return &TicketStore{tickets: make(map[string]wsTicket)}
+}
+
+// Issue gera um novo ticket para o usuário, válido por wsTicketTTL.
+func (ts *TicketStore) Issue(userID uint, username string) (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	ticket := hex.EncodeToString(buf)
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	now := time.Now()
+	for key, t := range ts.tickets { // Limpeza preguiçosa dos tickets expirados
+		if now.After(t.expiresAt) {
+			delete(ts.tickets, key)
+		}
+	}
+	ts.tickets[ticket] = wsTicket{userID: userID, username: username, expiresAt: now.Add(wsTicketTTL)}
+	return ticket, nil
+}
+
+// Redeem consome o ticket. Um ticket só pode ser usado uma vez.
+func (ts *TicketStore) Redeem(ticket string) (*Claims, bool) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	t, ok := ts.tickets[ticket]
+	if !ok {
+		return nil, false
+	}
+	delete(ts.tickets, ticket)
+	if time.Now().After(t.expiresAt) {
+		return nil, false
+	}
+	return &Claims{UserID: t.userID, Username: t.username}, true
+}
+
+// extractBearerToken retorna o token de um cabeçalho "Bearer <token>".
+func extractBearerToken(authHeader string) (string, bool) {
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+		return "", false
+	}
+	return parts[1], true
+}
+
+// authenticateUpgrade identifica o usuário de um pedido de upgrade WebSocket.
+// Aceita, nesta ordem: cabeçalho Authorization, subprotocolo discordia.bearer ou ?ticket=.
+// Quando o subprotocolo é usado, retorna o cabeçalho de resposta que deve ser enviado no upgrade.
+func authenticateUpgrade(r *http.Request, tickets *TicketStore) (*Claims, http.Header, error) {
+	if authHeader := r.Header.Get("Authorization"); authHeader != "" {
+		tokenString, ok := extractBearerToken(authHeader)
+		if !ok {
+			return nil, nil, errors.New("formato do cabeçalho de autorização inválido")
+		}
+		claims, err := ValidateJWT(tokenString)
+		return claims, nil, err
+	}
+
+	protocols := websocketProtocols(r)
+	if len(protocols) == 2 && protocols[0] == wsBearerProtocol {
+		claims, err := ValidateJWT(protocols[1])
+		if err != nil {
+			return nil, nil, err
+		}
+		return claims, http.Header{"Sec-WebSocket-Protocol": {wsBearerProtocol}}, nil
+	}
+
+	if ticket := r.URL.Query().Get("ticket"); ticket != "" {
+		claims, ok := tickets.Redeem(ticket)
+		if !ok {
+			return nil, nil, errors.New("ticket inválido ou expirado")
+		}
+		return claims, nil, nil
+	}
+
+	return nil, nil, errWSUnauthenticated
+}
+
+func websocketProtocols(r *http.Request) []string {
+	var protocols []string
+	for _, header := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, p := range strings.Split(header, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				protocols = append(protocols, p)
+			}
+		}
+	}
+	return protocols
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// Tempo máximo para escrever uma mensagem no peer.
+	writeWait = 10 * time.Second
+	// Tamanho máximo de uma mensagem recebida do peer.
+	maxMessageSize = 64 * 1024
+	// Quantidade de mensagens que podem aguardar na fila de envio de um cliente.
+	sendBufferSize = 256
+)
+
+// Client é a ponte entre uma conexão WebSocket e o Hub.
+// Somente a goroutine de writePump escreve na conexão; todas as
+// outras partes do código enfileiram mensagens em send.
+type Client struct {
+	hub  *Hub
+	conn *websocket.Conn
+	send chan []byte
+
+	// userID é o usuário autenticado no upgrade; não muda durante a vida da conexão.
+	userID uint
+
+	// identified indica que a conexão já tem uma sessão (IDENTIFY ou RESUME).
+	// Só é lido/escrito pela goroutine de leitura.
+	identified bool
+
+	mu     sync.Mutex
+	closed bool
+}
+
+func newClient(hub *Hub, conn *websocket.Conn, userID uint) *Client {
+	return &Client{
+		hub:    hub,
+		conn:   conn,
+		send:   make(chan []byte, sendBufferSize),
+		userID: userID,
+	}
+}
+
+// enqueue coloca data na fila de envio sem bloquear.
+// Retorna false se o cliente já foi fechado ou se a fila estiver cheia;
+// no segundo caso a fila é fechada e a conexão será encerrada.
+func (c *Client) enqueue(data []byte) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return false
+	}
+	select {
+	case c.send <- data:
+		return true
+	default:
+		c.closed = true
+		close(c.send)
+		return false
+	}
+}
+
+// closeSend fecha a fila de envio uma única vez.
+func (c *Client) closeSend() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.closed {
+		c.closed = true
+		close(c.send)
+	}
+}
+
+// sendFrame enfileira um frame do gateway que não faz parte da sequência da
+// sessão (HELLO, HEARTBEAT_ACK, INVALID_SESSION).
+func (c *Client) sendFrame(op int, d interface{}) {
+	if data := encodeGatewayFrame(op, "", 0, d); data != nil {
+		c.enqueue(data)
+	}
+}
+
+// sendError despacha um evento de erro apenas para este cliente.
+func (c *Client) sendError(message string) {
+	c.hub.SendToClient(c, "error", gin.H{"message": message})
+}
+
+// extendDeadline adia o prazo de leitura; chamado a cada heartbeat recebido.
+// Um cliente que para de enviar heartbeats é tratado como conexão zumbi.
+func (c *Client) extendDeadline() {
+	c.conn.SetReadDeadline(time.Now().Add(heartbeatTimeout))
+}
+
+// readPump lê as mensagens da conexão e as entrega para handle.
+// Ao sair, remove o cliente do hub e fecha a conexão.
+func (c *Client) readPump(handle func(c *Client, data []byte)) {
+	defer func() {
+		c.hub.unregister <- c
+		c.conn.Close()
+	}()
+
+	c.conn.SetReadLimit(maxMessageSize)
+	c.extendDeadline()
+
+	for {
+		messageType, data, err := c.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+				log.Printf("Erro de leitura no WebSocket: %v", err)
+			}
+			return
+		}
+		if messageType == websocket.TextMessage {
+			handle(c, data)
+		}
+	}
+}
+
+// writePump envia as mensagens da fila para a conexão.
+// É a única goroutine que escreve na conexão.
+func (c *Client) writePump() {
+	defer c.conn.Close()
+
+	for data := range c.send {
+		c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+		if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+			return
+		}
+	}
+	// O hub fechou a fila (desconexão, sessão substituída ou cliente lento).
+	c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+}
@@ -2,15 +2,15 @@
 package handlers
 
 import (
-	// ... (outros imports: gin, log, net/http, gorm) ...
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"encoding/json"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
-	"github.com/gustavoverneck/discordia/server/models"
 	"gorm.io/gorm"
 )
 
@@ -26,176 +26,301 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
-type WebSocketMessage struct {
-	Type    string      `json:"type"`
-	Payload interface{} `json:"payload"` // Usamos interface{} para lidar com diferentes payloads
-}
-
 // Payload específico para 'join_channel'
 type JoinChannelPayload struct {
-	ChannelID uint   `json:"channelId"`
-	Token     string `json:"token"`
+	ChannelID uint `json:"channelId"`
 }
 
 // Payload específico para 'new_message'
 type NewMessagePayload struct {
+	ChannelID       uint   `json:"channelId"`
+	Content         string `json:"content"`
+	ParentMessageID *uint  `json:"parentMessageId,omitempty"` // Preenchido ao responder uma mensagem
+}
+
+type EditMessagePayload struct {
 	ChannelID uint   `json:"channelId"`
+	MessageID uint   `json:"messageId"`
 	Content   string `json:"content"`
 }
 
-var clients = make(map[*websocket.Conn]uint) // conn -> userID
-var channelSubscriptions = make(map[uint]map[*websocket.Conn]bool)
+type DeleteMessagePayload struct {
+	ChannelID uint   `json:"channelId"`
+	MessageID uint   `json:"messageId"`
+	Reason    string `json:"reason,omitempty"` // Registrado na auditoria quando um moderador apaga a mensagem
+}
+
+// UpdatePresencePayload altera o status escolhido e/ou informa se o usuário está ausente neste dispositivo.
+type UpdatePresencePayload struct {
+	Status *string `json:"status,omitempty"` // online, idle, dnd ou invisible
+	AFK    *bool   `json:"afk,omitempty"`
+}
+
+// WSHandler agrupa o handler de WebSocket e suas dependências.
+type WSHandler struct {
+	DB      *gorm.DB
+	Hub     *Hub
+	Tickets *TicketStore
+}
+
+// NewWSHandler é o construtor para WSHandler.
+func NewWSHandler(db *gorm.DB, hub *Hub) *WSHandler {
+	return &WSHandler{DB: db, Hub: hub, Tickets: NewTicketStore()}
+}
+
+// IssueTicket gera um ticket curto e de uso único para abrir o WebSocket
+// via /ws/chat?ticket=..., útil para clientes que não enviam cabeçalhos no upgrade.
+func (wh *WSHandler) IssueTicket(c *gin.Context) {
+	rawUserID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+		return
+	}
+	userID := rawUserID.(uint)
+	username, _ := c.Get("username")
+	usernameStr, _ := username.(string)
+
+	ticket, err := wh.Tickets.Issue(userID, usernameStr)
+	if err != nil {
+		log.Printf("Erro ao gerar ticket de WebSocket para usuário %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Falha ao gerar ticket."})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"ticket":    ticket,
+		"expiresIn": int(wsTicketTTL.Seconds()),
+	})
+}
+
+// HandleWebSocketChat autentica o pedido, faz o upgrade da conexão, registra o
+// cliente no Hub, envia o HELLO e inicia as goroutines de leitura e escrita.
+// Pedidos sem credenciais válidas são rejeitados com 401 antes do upgrade.
+// Após o HELLO o cliente deve enviar IDENTIFY (nova sessão) ou RESUME.
+func (wh *WSHandler) HandleWebSocketChat(c *gin.Context) {
+	if v := c.Query("v"); v != "" && v != strconv.Itoa(GatewayVersion) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Versão do gateway não suportada."})
+		return
+	}
+
+	claims, responseHeader, err := authenticateUpgrade(c.Request, wh.Tickets)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
 
-// HandleWebSocketChat é um exemplo de handler. Você precisará de uma estrutura mais robusta
-// para gerenciar múltiplos clientes, canais, autenticação, etc.
-// Este é um ponto de partida MUITO SIMPLES.
-func HandleWebSocketChat(w http.ResponseWriter, r *http.Request, db *gorm.DB) { // Recebe DB
-	conn, err := upgrader.Upgrade(w, r, nil)
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, responseHeader)
 	if err != nil {
 		log.Printf("Falha ao fazer upgrade para WebSocket: %v", err)
 		return
 	}
-	defer func() {
-		// Limpeza ao desconectar: remover cliente de 'clients' e de todas as 'channelSubscriptions'
-		userID, ok := clients[conn]
-		if ok {
-			for channelID, connsInChannel := range channelSubscriptions {
-				if _, inChannel := connsInChannel[conn]; inChannel {
-					delete(channelSubscriptions[channelID], conn)
-					log.Printf("Usuário %d removido do canal %d nas subscriptions do WebSocket", userID, channelID)
-					// Opcional: notificar outros no canal que o usuário saiu
-				}
+
+	client := newClient(wh.Hub, conn, claims.UserID)
+	wh.Hub.register <- client
+	log.Printf("Usuário %d conectado via WebSocket (conexão %p)", client.userID, client)
+
+	client.sendFrame(OpHello, HelloPayload{
+		HeartbeatInterval: heartbeatInterval.Milliseconds(),
+		Version:           GatewayVersion,
+	})
+
+	go client.writePump()
+	go client.readPump(wh.handleMessage)
+}
+
+// handleMessage processa um frame recebido de um cliente.
+// É chamado apenas pela goroutine de leitura do cliente.
+func (wh *WSHandler) handleMessage(client *Client, p []byte) {
+	var frame GatewayPayload
+	if err := json.Unmarshal(p, &frame); err != nil {
+		log.Printf("Erro ao decodificar JSON do WebSocket: %v", err)
+		client.sendError("Formato de mensagem inválido")
+		return
+	}
+
+	switch frame.Op {
+	case OpHeartbeat:
+		client.extendDeadline()
+		client.sendFrame(OpHeartbeatACK, nil)
+
+	case OpIdentify:
+		if client.identified {
+			client.sendError("Conexão já identificada.")
+			return
+		}
+		client.identified = wh.Hub.Identify(client, loadStatusPreference(wh.DB, client.userID))
+
+	case OpResume:
+		var resume ResumePayload
+		if err := json.Unmarshal(frame.D, &resume); err != nil || resume.SessionID == "" {
+			client.sendError("Payload de RESUME inválido.")
+			return
+		}
+		if client.identified {
+			client.sendError("Conexão já identificada.")
+			return
+		}
+		if !wh.Hub.Resume(client, resume.SessionID, resume.Seq) {
+			// O cliente deve iniciar uma nova sessão com IDENTIFY.
+			client.sendFrame(OpInvalidSession, false)
+			return
+		}
+		client.identified = true
+		log.Printf("Usuário %d retomou a sessão %s a partir da sequência %d", client.userID, resume.SessionID, resume.Seq)
+
+	case OpCommand:
+		if !client.identified {
+			client.sendError("Envie IDENTIFY ou RESUME antes de enviar comandos.")
+			return
+		}
+		if frame.T != "update_presence" {
+			wh.Hub.MarkActive(client, false) // Qualquer comando conta como atividade
+		}
+		wh.handleCommand(client, frame.T, frame.D)
+
+	default:
+		log.Printf("Opcode WebSocket desconhecido: %d", frame.Op)
+		client.sendError("Opcode desconhecido.")
+	}
+}
+
+// handleCommand processa um comando (op 3) de um cliente já identificado.
+func (wh *WSHandler) handleCommand(client *Client, commandType string, d json.RawMessage) {
+	switch commandType {
+	case "join_channel":
+		var joinPayload JoinChannelPayload
+		if err := json.Unmarshal(d, &joinPayload); err != nil {
+			log.Printf("Erro ao decodificar payload de join_channel: %v", err)
+			client.sendError("Payload de join_channel inválido.")
+			return
+		}
+
+		// O usuário já foi autenticado no upgrade; falta verificar se pode ver o canal.
+		if _, _, err := authorizeChannel(wh.DB, client.userID, joinPayload.ChannelID, PermissionViewChannel); err != nil {
+			wsAuthzError(client, err)
+			return
+		}
+
+		// Inscrever a sessão no canal através do hub.
+		wh.Hub.Subscribe(client, joinPayload.ChannelID)
+		log.Printf("Usuário %d (conexão %p) inscrito no canal %d", client.userID, client, joinPayload.ChannelID)
+		wh.Hub.SendToClient(client, "join_success", gin.H{"channelId": joinPayload.ChannelID, "message": "Inscrito no canal com sucesso!"})
+
+	case "update_presence":
+		var presencePayload UpdatePresencePayload
+		if err := json.Unmarshal(d, &presencePayload); err != nil {
+			client.sendError("Payload de update_presence inválido.")
+			return
+		}
+		if presencePayload.Status != nil {
+			if !validStatusPreference(*presencePayload.Status) {
+				client.sendError("Status inválido.")
+				return
+			}
+			if err := setStatusPreference(wh.DB, wh.Hub, client.userID, *presencePayload.Status); err != nil {
+				log.Printf("Erro ao atualizar status do usuário %d: %v", client.userID, err)
+				client.sendError("Falha ao atualizar status.")
+				return
 			}
 		}
-		delete(clients, conn)
-		conn.Close()
-		log.Println("Cliente desconectado do WebSocket e subscriptions limpas")
-	}()
+		// Sem afk explícito, o próprio comando conta como atividade
+		wh.Hub.MarkActive(client, presencePayload.AFK != nil && *presencePayload.AFK)
 
-	log.Println("Novo cliente conectado via WebSocket")
+	case "ack_message":
+		var ackPayload AckMessagePayload
+		if err := json.Unmarshal(d, &ackPayload); err != nil || ackPayload.MessageID == 0 {
+			client.sendError("Payload de ack_message inválido.")
+			return
+		}
+		if _, err := acknowledge(wh.DB, wh.Hub, client.userID, ackPayload.ChannelID, ackPayload.MessageID); err != nil {
+			wsAuthzError(client, err)
+			return
+		}
 
-	// Loop para ler mensagens do cliente
-	for {
-		messageType, p, err := conn.ReadMessage() // p é []byte
+	case "typing_start":
+		var typingPayload TypingStartPayload
+		if err := json.Unmarshal(d, &typingPayload); err != nil {
+			client.sendError("Payload de typing_start inválido.")
+			return
+		}
+		// Só quem pode enviar mensagens no canal pode aparecer digitando
+		channel, _, err := authorizeChannel(wh.DB, client.userID, typingPayload.ChannelID, PermissionViewChannel|PermissionSendMessages)
 		if err != nil {
-			// ... (tratamento de erro de leitura como antes) ...
-			break
+			wsAuthzError(client, err)
+			return
 		}
+		if err := checkChannelTimeout(wh.DB, channel, client.userID); err != nil {
+			wsAuthzError(client, err)
+			return
+		}
+		wh.Hub.StartTyping(client.userID, typingPayload.ChannelID)
 
-		if messageType == websocket.TextMessage {
-			log.Printf("Mensagem de texto recebida: %s", string(p))
+	case "new_message":
+		var newMsgPayload NewMessagePayload
+		if err := json.Unmarshal(d, &newMsgPayload); err != nil {
+			log.Printf("Erro ao decodificar payload de new_message: %v", err)
+			client.sendError("Payload de new_message inválido.")
+			return
+		}
 
-			var msg WebSocketMessage
-			if err := json.Unmarshal(p, &msg); err != nil {
-				log.Printf("Erro ao decodificar JSON do WebSocket: %v", err)
-				// Opcional: enviar mensagem de erro de volta ao cliente
-				// conn.WriteJSON(gin.H{"error": "Formato de mensagem inválido"})
-				continue
-			}
+		// O autor é o usuário associado à conexão no upgrade
+		if _, err := createMessage(wh.DB, wh.Hub, client.userID, newMsgPayload, nil); err != nil {
+			wsAuthzError(client, err)
+			return
+		}
 
-			switch msg.Type {
-			case "join_channel":
-				payloadBytes, _ := json.Marshal(msg.Payload)
-				var joinPayload JoinChannelPayload
-				if err := json.Unmarshal(payloadBytes, &joinPayload); err != nil {
-					log.Printf("Erro ao decodificar payload de join_channel: %v", err)
-					continue
-				}
-
-				log.Printf("Cliente tentando entrar no canal: %d com token: %s", joinPayload.ChannelID, joinPayload.Token)
-
-				// 1. Autenticar o token
-				claims, err := ValidateJWT(joinPayload.Token) // Sua função ValidateJWT
-				if err != nil {
-					log.Printf("Token inválido para join_channel: %v", err)
-					conn.WriteJSON(gin.H{"type": "error", "payload": "Token inválido ou expirado."})
-					// conn.Close() // Pode fechar a conexão se a autenticação falhar
-					continue
-				}
-				userID := claims.UserID
-				clients[conn] = userID // Associa a conexão ao userID
-
-				// 2. Inscrever no canal (lógica MUITO SIMPLIFICADA)
-				if channelSubscriptions[joinPayload.ChannelID] == nil {
-					channelSubscriptions[joinPayload.ChannelID] = make(map[*websocket.Conn]bool)
-				}
-				channelSubscriptions[joinPayload.ChannelID][conn] = true
-				log.Printf("Usuário %d (conexão %p) inscrito no canal %d", userID, conn, joinPayload.ChannelID)
-				conn.WriteJSON(gin.H{"type": "join_success", "payload": gin.H{"channelId": joinPayload.ChannelID, "message": "Inscrito no canal com sucesso!"}})
-
-			case "new_message":
-				payloadBytes, _ := json.Marshal(msg.Payload)
-				var newMsgPayload NewMessagePayload
-				if err := json.Unmarshal(payloadBytes, &newMsgPayload); err != nil {
-					log.Printf("Erro ao decodificar payload de new_message: %v", err)
-					continue
-				}
-
-				// 1. Obter userID da conexão (deve ter sido feito no join_channel)
-				authorID, authenticated := clients[conn]
-				if !authenticated {
-					log.Println("Mensagem recebida de conexão não autenticada/não associada a usuário.")
-					conn.WriteJSON(gin.H{"type": "error", "payload": "Não autenticado para enviar mensagem."})
-					continue
-				}
-
-				// Opcional: Verificar se o usuário está realmente inscrito no newMsgPayload.ChannelID
-
-				// 2. Salvar mensagem no banco de dados
-				dbMessage := models.Message{
-					ChannelID: newMsgPayload.ChannelID,
-					AuthorID:  authorID,
-					Content:   newMsgPayload.Content,
-				}
-				if result := db.Create(&dbMessage); result.Error != nil {
-					log.Printf("Erro ao salvar mensagem no DB: %v", result.Error)
-					conn.WriteJSON(gin.H{"type": "error", "payload": "Falha ao salvar mensagem."})
-					continue
-				}
-
-				// Precisamos carregar o autor para enviar na resposta
-				var authorDetails models.User
-				db.First(&authorDetails, authorID) // Simples; adicione tratamento de erro
-
-				// Formatar para MessageResponse DTO
-				messageForBroadcast := MessageResponse{ // Reutilize o DTO MessageResponse
-					ID:        dbMessage.ID,
-					Content:   dbMessage.Content,
-					CreatedAt: dbMessage.CreatedAt,
-					Author: struct {
-						ID        uint   `json:"id"`
-						Username  string `json:"username"`
-						AvatarURL string `json:"avatarUrl,omitempty"`
-					}{
-						ID:        authorDetails.ID,
-						Username:  authorDetails.Username,
-						AvatarURL: authorDetails.AvatarURL,
-					},
-					ChannelID: dbMessage.ChannelID,
-				}
-
-				// 3. Transmitir (Broadcast) para todos no canal
-				log.Printf("Transmitindo mensagem para canal %d", newMsgPayload.ChannelID)
-				if connsInChannel, ok := channelSubscriptions[newMsgPayload.ChannelID]; ok {
-					for clientConn := range connsInChannel {
-						// Não enviar para si mesmo se já estiver atualizando a UI otimisticamente,
-						// ou enviar para todos e o frontend decide.
-						// if clientConn == conn { continue } // Exemplo: não enviar para o remetente
-
-						err := clientConn.WriteJSON(messageForBroadcast) // Envia o DTO
-						if err != nil {
-							log.Printf("Erro ao transmitir mensagem para cliente %p: %v", clientConn, err)
-							// Opcional: remover este cliente se houver erro de escrita (pode estar desconectado)
-							// clientConn.Close()
-							// delete(clients, clientConn)
-							// delete(channelSubscriptions[newMsgPayload.ChannelID], clientConn)
-						}
-					}
-				}
-
-			default:
-				log.Printf("Tipo de mensagem WebSocket desconhecido: %s", msg.Type)
-			}
+	case "edit_message":
+		var editPayload EditMessagePayload
+		if err := json.Unmarshal(d, &editPayload); err != nil {
+			log.Printf("Erro ao decodificar payload de edit_message: %v", err)
+			client.sendError("Payload de edit_message inválido.")
+			return
+		}
+		if !validMessageContent(editPayload.Content) {
+			client.sendError("O conteúdo da mensagem não pode ser vazio.")
+			return
+		}
+
+		message, err := editMessage(wh.DB, client.userID, editPayload.ChannelID, editPayload.MessageID, editPayload.Content)
+		if err != nil {
+			wsAuthzError(client, err)
+			return
+		}
+		response, err := buildMessageResponse(wh.DB, *message)
+		if err != nil {
+			log.Printf("Erro ao montar resposta da mensagem %d: %v", message.ID, err)
+			client.sendError("Falha ao buscar mensagem.")
+			return
+		}
+		wh.Hub.BroadcastToChannel(editPayload.ChannelID, "message_update", response)
+
+	case "delete_message":
+		var deletePayload DeleteMessagePayload
+		if err := json.Unmarshal(d, &deletePayload); err != nil {
+			log.Printf("Erro ao decodificar payload de delete_message: %v", err)
+			client.sendError("Payload de delete_message inválido.")
+			return
+		}
+
+		reason := truncateRunes(strings.TrimSpace(deletePayload.Reason), maxAuditReasonLength)
+		if err := deleteMessage(wh.DB, client.userID, deletePayload.ChannelID, deletePayload.MessageID, reason); err != nil {
+			wsAuthzError(client, err)
+			return
 		}
+		wh.Hub.BroadcastToChannel(deletePayload.ChannelID, "message_delete", MessageDeletePayload{ID: deletePayload.MessageID, ChannelID: deletePayload.ChannelID})
+
+	default:
+		log.Printf("Comando WebSocket desconhecido: %s", commandType)
+		client.sendError("Comando desconhecido.")
+	}
+}
+
+// wsAuthzError envia ao cliente o frame de erro correspondente a uma falha de autorização.
+func wsAuthzError(client *Client, err error) {
+	if authzErrorStatus(err) == http.StatusInternalServerError {
+		log.Printf("Erro ao verificar autorização do usuário %d: %v", client.userID, err)
+		client.sendError("Erro ao verificar permissões.")
+		return
 	}
+	client.sendError(err.Error())
 }
@@ -0,0 +1,153 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	webpalette "image/color/palette"
+	"image/draw"
+	"image/gif"
+)
+
+// animatedCrops é Crops para GIFs animados, preservando o tempo de cada quadro
+// e o número de repetições. Os quadros são compostos na tela inteira
+// (respeitando o descarte de cada um) antes de serem recortados e reduzidos,
+// então cada quadro gerado é completo.
+func animatedCrops(anim *gif.GIF, aspectW, aspectH int, widths []int) ([]Variant, error) {
+	w, h := anim.Config.Width, anim.Config.Height
+	if w < 1 || h < 1 {
+		return nil, ErrInvalidImage
+	}
+	if w*h*len(anim.Image) > MaxAnimationPixels {
+		return nil, ErrTooLarge
+	}
+
+	canvas := image.NewRGBA(image.Rect(0, 0, w, h))
+	crop := cropRect(canvas.Bounds(), aspectW, aspectH)
+	outputs := make([]*gif.GIF, len(widths))
+	for i := range outputs {
+		outputs[i] = &gif.GIF{LoopCount: anim.LoopCount}
+	}
+
+	var saved *image.RGBA
+	for i, frame := range anim.Image {
+		disposal := byte(gif.DisposalNone)
+		if i < len(anim.Disposal) {
+			disposal = anim.Disposal[i]
+		}
+		if disposal == gif.DisposalPrevious {
+			saved = image.NewRGBA(canvas.Bounds())
+			copy(saved.Pix, canvas.Pix)
+		}
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+
+		cropped := canvas.SubImage(crop).(*image.RGBA)
+		for j, width := range widths {
+			w, h := scaledSize(crop, width)
+			outputs[j].Image = append(outputs[j].Image, quantize(resize(cropped, w, h), frame.Palette))
+			outputs[j].Delay = append(outputs[j].Delay, anim.Delay[i])
+			// Cada quadro gerado cobre a tela toda; limpar antes do próximo evita que as
+			// partes transparentes mostrem o quadro anterior
+			outputs[j].Disposal = append(outputs[j].Disposal, gif.DisposalBackground)
+		}
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, frame.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			if saved != nil {
+				copy(canvas.Pix, saved.Pix)
+			}
+		}
+	}
+
+	variants := make([]Variant, 0, len(widths))
+	for i, width := range widths {
+		w, h := scaledSize(crop, width)
+		var buf bytes.Buffer
+		if err := gif.EncodeAll(&buf, outputs[i]); err != nil {
+			return nil, err
+		}
+		variants = append(variants, Variant{
+			Size:        width,
+			Width:       w,
+			Height:      h,
+			Data:        buf.Bytes(),
+			ContentType: "image/gif",
+			Ext:         ".gif",
+		})
+	}
+	return variants, nil
+}
+
+// quantize converte o quadro para a paleta original do GIF. Pixels com menos
+// de 50% de opacidade viram o índice transparente, acrescentado à paleta se preciso.
+func quantize(img *image.RGBA, palette color.Palette) *image.Paletted {
+	transparent := -1
+	opaque := make([]int, 0, len(palette))
+	for i, c := range palette {
+		if _, _, _, a := c.RGBA(); a == 0 {
+			if transparent < 0 {
+				transparent = i
+			}
+		} else {
+			opaque = append(opaque, i)
+		}
+	}
+	if transparent < 0 && !img.Opaque() {
+		palette = append(color.Palette(nil), palette...)
+		if len(palette) < 256 {
+			palette = append(palette, color.RGBA{})
+		} else {
+			palette[len(palette)-1] = color.RGBA{}
+			opaque = opaque[:len(opaque)-1]
+		}
+		transparent = len(palette) - 1
+	}
+	if len(opaque) == 0 { // Paleta só com transparência: usa a paleta padrão
+		palette = append(color.Palette{color.RGBA{}}, webpalette.WebSafe...)
+		transparent = 0
+		for i := 1; i < len(palette); i++ {
+			opaque = append(opaque, i)
+		}
+	}
+
+	b := img.Bounds()
+	dst := image.NewPaletted(image.Rect(0, 0, b.Dx(), b.Dy()), palette)
+	cache := make(map[[3]uint8]uint8)
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			p := img.Pix[img.PixOffset(b.Min.X+x, b.Min.Y+y):]
+			if p[3] < 128 {
+				dst.Pix[y*dst.Stride+x] = uint8(transparent)
+				continue
+			}
+			// Desfaz o alfa pré-multiplicado antes de comparar com a paleta
+			key := [3]uint8{unpremultiply(p[0], p[3]), unpremultiply(p[1], p[3]), unpremultiply(p[2], p[3])}
+			index, ok := cache[key]
+			if !ok {
+				index = nearest(palette, opaque, key)
+				cache[key] = index
+			}
+			dst.Pix[y*dst.Stride+x] = index
+		}
+	}
+	return dst
+}
+
+func unpremultiply(v, a uint8) uint8 {
+	return uint8(min(255, (int(v)*255+int(a)/2)/int(a)))
+}
+
+// nearest devolve o índice da cor opaca da paleta mais próxima de c.
+func nearest(palette color.Palette, candidates []int, c [3]uint8) uint8 {
+	best, bestDistance := candidates[0], -1
+	for _, i := range candidates {
+		r, g, b, _ := palette[i].RGBA()
+		dr, dg, db := int(r>>8)-int(c[0]), int(g>>8)-int(c[1]), int(b>>8)-int(c[2])
+		if d := dr*dr + dg*dg + db*db; bestDistance < 0 || d < bestDistance {
+			best, bestDistance = i, d
+		}
+	}
+	return uint8(best)
+}
@@ -0,0 +1,162 @@
+// Package imaging processa as imagens enviadas pelos usuários: valida e
+// decodifica, remove metadados (EXIF, GPS, XMP), aplica a orientação EXIF e
+// gera versões recortadas e redimensionadas para ícones, avatares e banners.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+)
+
+const (
+	// MaxPixels limita a área de imagens estáticas, protegendo contra "bombas" de descompressão.
+	MaxPixels = 40_000_000
+	// MaxAnimationPixels limita a soma das áreas de todos os quadros de um GIF animado.
+	MaxAnimationPixels = 100_000_000
+	// MaxDimension é a maior largura ou altura aceita.
+	MaxDimension = 8192
+
+	jpegQuality = 85
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("formato de imagem não suportado (use PNG, JPEG ou GIF)")
+	ErrTooLarge          = errors.New("imagem grande demais")
+	ErrInvalidImage      = errors.New("imagem inválida ou corrompida")
+)
+
+// Variant é uma versão da imagem re-codificada em um tamanho.
+type Variant struct {
+	Size        int // Largura pedida; a imagem nunca é ampliada, então Width pode ser menor
+	Width       int
+	Height      int
+	Data        []byte
+	ContentType string
+	Ext         string
+}
+
+// checkConfig valida o formato e as dimensões antes de decodificar a imagem inteira.
+func checkConfig(data []byte) (image.Config, string, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		if errors.Is(err, image.ErrFormat) {
+			return config, "", ErrUnsupportedFormat
+		}
+		return config, "", ErrInvalidImage
+	}
+	switch format {
+	case "png", "jpeg", "gif":
+	default:
+		return config, "", ErrUnsupportedFormat
+	}
+	if config.Width < 1 || config.Height < 1 {
+		return config, "", ErrInvalidImage
+	}
+	if config.Width > MaxDimension || config.Height > MaxDimension || config.Width*config.Height > MaxPixels {
+		return config, "", ErrTooLarge
+	}
+	return config, format, nil
+}
+
+// decode decodifica uma imagem estática (ou o primeiro quadro de um GIF) já
+// com a orientação EXIF aplicada.
+func decode(data []byte, format string) (image.Image, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	if format == "jpeg" {
+		img = orient(img, jpegOrientation(data))
+	}
+	return img, nil
+}
+
+// Crops gera, para cada largura pedida, uma versão da imagem recortada no
+// centro na proporção aspectW:aspectH, sem metadados. GIFs animados continuam
+// animados; imagens estáticas viram PNG quando têm transparência e JPEG caso contrário.
+func Crops(data []byte, aspectW, aspectH int, widths []int) ([]Variant, error) {
+	_, format, err := checkConfig(data)
+	if err != nil {
+		return nil, err
+	}
+	if format == "gif" {
+		anim, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil {
+			return nil, ErrInvalidImage
+		}
+		if len(anim.Image) > 1 {
+			return animatedCrops(anim, aspectW, aspectH, widths)
+		}
+	}
+
+	img, err := decode(data, format)
+	if err != nil {
+		return nil, err
+	}
+	cropped := toRGBA(subImage(img, cropRect(img.Bounds(), aspectW, aspectH)))
+	opaque := cropped.Opaque()
+
+	variants := make([]Variant, 0, len(widths))
+	for _, width := range widths {
+		w, h := scaledSize(cropped.Bounds(), width)
+		resized := resize(cropped, w, h)
+		variant := Variant{Size: width, Width: w, Height: h}
+		var buf bytes.Buffer
+		if opaque {
+			err = jpeg.Encode(&buf, resized, &jpeg.Options{Quality: jpegQuality})
+			variant.ContentType, variant.Ext = "image/jpeg", ".jpg"
+		} else {
+			err = png.Encode(&buf, resized)
+			variant.ContentType, variant.Ext = "image/png", ".png"
+		}
+		if err != nil {
+			return nil, err
+		}
+		variant.Data = buf.Bytes()
+		variants = append(variants, variant)
+	}
+	return variants, nil
+}
+
+// cropRect devolve o maior retângulo centralizado em b na proporção aspectW:aspectH.
+func cropRect(b image.Rectangle, aspectW, aspectH int) image.Rectangle {
+	w, h := b.Dx(), b.Dx()*aspectH/aspectW
+	if h > b.Dy() {
+		w, h = b.Dy()*aspectW/aspectH, b.Dy()
+	}
+	w, h = max(w, 1), max(h, 1)
+	x0 := b.Min.X + (b.Dx()-w)/2
+	y0 := b.Min.Y + (b.Dy()-h)/2
+	return image.Rect(x0, y0, x0+w, y0+h)
+}
+
+// scaledSize devolve as dimensões de b reduzidas para a largura pedida,
+// mantendo a proporção. Imagens menores não são ampliadas.
+func scaledSize(b image.Rectangle, width int) (int, int) {
+	if width >= b.Dx() {
+		return b.Dx(), b.Dy()
+	}
+	return width, max(1, (b.Dy()*width+b.Dx()/2)/b.Dx())
+}
+
+func subImage(img image.Image, r image.Rectangle) image.Image {
+	if s, ok := img.(interface {
+		SubImage(image.Rectangle) image.Image
+	}); ok {
+		return s.SubImage(r)
+	}
+	return toRGBA(img).SubImage(r.Sub(img.Bounds().Min))
+}
+
+// toRGBA copia a imagem para um *image.RGBA (alfa pré-multiplicado) com origem em (0, 0).
+func toRGBA(img image.Image) *image.RGBA {
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Src)
+	return dst
+}
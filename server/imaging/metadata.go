@@ -0,0 +1,210 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image/jpeg"
+)
+
+// StripMetadata remove da imagem os metadados que podem expor o usuário
+// (EXIF com localização GPS, XMP, IPTC, comentários), sem re-codificar os
+// pixels. Fotos JPEG giradas via EXIF são re-codificadas já na orientação
+// correta, já que a tag de orientação também é removida. Formatos sem
+// suporte (GIF, BMP, ...) e imagens inválidas são devolvidos sem alteração.
+func StripMetadata(data []byte, mediaType string) []byte {
+	switch mediaType {
+	case "image/jpeg":
+		if orientation := jpegOrientation(data); orientation > 1 && orientation <= 8 {
+			if reencoded, err := reencodeJPEG(data); err == nil {
+				return reencoded
+			}
+		}
+		if stripped, ok := stripJPEG(data); ok {
+			return stripped
+		}
+	case "image/png":
+		if stripped, ok := stripPNG(data); ok {
+			return stripped
+		}
+	case "image/webp":
+		if stripped, ok := stripWebP(data); ok {
+			return stripped
+		}
+	}
+	return data
+}
+
+// reencodeJPEG decodifica o JPEG, aplica a orientação EXIF e o codifica de novo, sem metadados.
+func reencodeJPEG(data []byte) ([]byte, error) {
+	_, format, err := checkConfig(data)
+	if err != nil {
+		return nil, err
+	}
+	img, err := decode(data, format)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// jpegSegments percorre os segmentos do cabeçalho JPEG até o início dos dados
+// da imagem (SOS). visit recebe o marcador e o segmento completo; ao final,
+// rest contém o restante do arquivo a partir do SOS.
+func jpegSegments(data []byte, visit func(marker byte, segment []byte)) (rest []byte, ok bool) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, false
+	}
+	i := 2
+	for i+4 <= len(data) {
+		if data[i] != 0xFF {
+			return nil, false
+		}
+		marker := data[i+1]
+		switch {
+		case marker == 0xFF: // Bytes de preenchimento
+			i++
+			continue
+		case marker == 0xDA: // SOS: daqui em diante são os dados comprimidos
+			return data[i:], true
+		case marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7): // Marcadores sem tamanho
+			visit(marker, data[i:i+2])
+			i += 2
+			continue
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 || i+2+length > len(data) {
+			return nil, false
+		}
+		visit(marker, data[i:i+2+length])
+		i += 2 + length
+	}
+	return nil, false
+}
+
+// stripJPEG remove os segmentos APP1 (EXIF/XMP), APP13 (IPTC) e COM, mantendo
+// os necessários para exibir a imagem corretamente (JFIF, perfil ICC, Adobe).
+func stripJPEG(data []byte) ([]byte, bool) {
+	out := make([]byte, 0, len(data))
+	out = append(out, 0xFF, 0xD8)
+	rest, ok := jpegSegments(data, func(marker byte, segment []byte) {
+		if marker == 0xE1 || marker == 0xED || marker == 0xFE {
+			return
+		}
+		out = append(out, segment...)
+	})
+	if !ok {
+		return nil, false
+	}
+	return append(out, rest...), true
+}
+
+// jpegOrientation lê a tag Orientation (0x0112) do EXIF; devolve 1 (normal) se ausente.
+func jpegOrientation(data []byte) int {
+	orientation := 1
+	jpegSegments(data, func(marker byte, segment []byte) {
+		if marker != 0xE1 || len(segment) < 10 || string(segment[4:10]) != "Exif\x00\x00" {
+			return
+		}
+		if o, ok := tiffOrientation(segment[10:]); ok {
+			orientation = o
+		}
+	})
+	return orientation
+}
+
+// tiffOrientation procura a tag Orientation no primeiro IFD de um cabeçalho TIFF.
+func tiffOrientation(tiff []byte) (int, bool) {
+	if len(tiff) < 8 {
+		return 0, false
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0, false
+	}
+	offset := int(order.Uint32(tiff[4:]))
+	if offset < 8 || offset+2 > len(tiff) {
+		return 0, false
+	}
+	count := int(order.Uint16(tiff[offset:]))
+	for i := 0; i < count; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 0, false
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			return int(order.Uint16(tiff[entry+8:])), true
+		}
+	}
+	return 0, false
+}
+
+// pngMetadataChunks são os chunks PNG que carregam metadados textuais ou EXIF.
+var pngMetadataChunks = map[string]bool{"eXIf": true, "tEXt": true, "zTXt": true, "iTXt": true, "tIME": true}
+
+// stripPNG remove os chunks de metadados, copiando os demais sem alteração.
+func stripPNG(data []byte) ([]byte, bool) {
+	const signature = "\x89PNG\r\n\x1a\n"
+	if len(data) < len(signature) || string(data[:len(signature)]) != signature {
+		return nil, false
+	}
+	out := make([]byte, 0, len(data))
+	out = append(out, signature...)
+	for i := len(signature); i < len(data); {
+		if i+12 > len(data) {
+			return nil, false
+		}
+		length := int(binary.BigEndian.Uint32(data[i:]))
+		end := i + 12 + length
+		if length < 0 || end > len(data) || end < i {
+			return nil, false
+		}
+		if !pngMetadataChunks[string(data[i+4:i+8])] {
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+	return out, true
+}
+
+// stripWebP remove os chunks EXIF e XMP do contêiner RIFF e limpa os
+// indicadores correspondentes no chunk VP8X.
+func stripWebP(data []byte) ([]byte, bool) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, false
+	}
+	out := make([]byte, 12, len(data))
+	copy(out, data[:12])
+	for i := 12; i < len(data); {
+		if i+8 > len(data) {
+			return nil, false
+		}
+		size := int(binary.LittleEndian.Uint32(data[i+4:]))
+		end := i + 8 + size + size%2 // Chunks de tamanho ímpar têm um byte de preenchimento
+		if size < 0 || end > len(data) || end < i {
+			return nil, false
+		}
+		switch fourCC := string(data[i : i+4]); fourCC {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			start := len(out)
+			out = append(out, data[i:end]...)
+			if size > 0 {
+				out[start+8] &^= 0x08 | 0x04 // Indicadores de EXIF e XMP
+			}
+		default:
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+	return out, true
+}
@@ -0,0 +1,128 @@
+package imaging
+
+import (
+	"image"
+)
+
+// weight é a contribuição de um pixel de origem para um pixel de destino.
+type weight struct {
+	index int
+	value float32
+}
+
+// boxWeights calcula, para cada pixel de destino, a fração de cada pixel de
+// origem coberta por ele (filtro de caixa, ideal para reduzir imagens).
+func boxWeights(src, dst int) [][]weight {
+	weights := make([][]weight, dst)
+	scale := float64(src) / float64(dst)
+	for i := range weights {
+		start, end := float64(i)*scale, float64(i+1)*scale
+		for j := int(start); j < src && float64(j) < end; j++ {
+			coverage := min(end, float64(j+1)) - max(start, float64(j))
+			if coverage > 0 {
+				weights[i] = append(weights[i], weight{index: j, value: float32(coverage / scale)})
+			}
+		}
+	}
+	return weights
+}
+
+// resize reduz src para w×h pela média das áreas cobertas. O cálculo é feito
+// em alfa pré-multiplicado, o que evita halos escuros nas bordas transparentes.
+func resize(src *image.RGBA, w, h int) *image.RGBA {
+	b := src.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	if w == sw && h == sh {
+		dst := image.NewRGBA(image.Rect(0, 0, w, h))
+		for y := 0; y < h; y++ {
+			copy(dst.Pix[y*dst.Stride:y*dst.Stride+w*4], src.Pix[src.PixOffset(b.Min.X, b.Min.Y+y):])
+		}
+		return dst
+	}
+
+	// Passo horizontal: sh linhas de w pixels
+	xWeights := boxWeights(sw, w)
+	tmp := make([]float32, sh*w*4)
+	for y := 0; y < sh; y++ {
+		row := src.Pix[src.PixOffset(b.Min.X, b.Min.Y+y):]
+		for x, ws := range xWeights {
+			var r, g, bl, a float32
+			for _, wt := range ws {
+				p := row[wt.index*4 : wt.index*4+4]
+				r += float32(p[0]) * wt.value
+				g += float32(p[1]) * wt.value
+				bl += float32(p[2]) * wt.value
+				a += float32(p[3]) * wt.value
+			}
+			t := tmp[(y*w+x)*4:]
+			t[0], t[1], t[2], t[3] = r, g, bl, a
+		}
+	}
+
+	// Passo vertical
+	yWeights := boxWeights(sh, h)
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y, ws := range yWeights {
+		for x := 0; x < w; x++ {
+			var r, g, bl, a float32
+			for _, wt := range ws {
+				t := tmp[(wt.index*w+x)*4:]
+				r += t[0] * wt.value
+				g += t[1] * wt.value
+				bl += t[2] * wt.value
+				a += t[3] * wt.value
+			}
+			d := dst.Pix[y*dst.Stride+x*4:]
+			d[0], d[1], d[2], d[3] = clamp8(r), clamp8(g), clamp8(bl), clamp8(a)
+		}
+	}
+	return dst
+}
+
+func clamp8(v float32) uint8 {
+	switch {
+	case v <= 0:
+		return 0
+	case v >= 255:
+		return 255
+	default:
+		return uint8(v + 0.5)
+	}
+}
+
+// orient aplica a transformação indicada pela tag Orientation do EXIF (1 a 8).
+func orient(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+	src := toRGBA(img)
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	dw, dh := w, h
+	if orientation >= 5 { // Orientações 5 a 8 trocam largura e altura
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // Espelhada na horizontal
+				dx, dy = w-1-x, y
+			case 3: // Girada 180°
+				dx, dy = w-1-x, h-1-y
+			case 4: // Espelhada na vertical
+				dx, dy = x, h-1-y
+			case 5: // Transposta
+				dx, dy = y, x
+			case 6: // Girada 90° no sentido horário
+				dx, dy = h-1-y, x
+			case 7: // Transversa
+				dx, dy = h-1-y, w-1-x
+			case 8: // Girada 90° no sentido anti-horário
+				dx, dy = y, w-1-x
+			}
+			copy(dst.Pix[dst.PixOffset(dx, dy):dst.PixOffset(dx, dy)+4], src.Pix[src.PixOffset(x, y):src.PixOffset(x, y)+4])
+		}
+	}
+	return dst
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/gorilla/websocket"
 	"github.com/gustavoverneck/discordia/server/database"
 	"github.com/gustavoverneck/discordia/server/handlers"
+	"github.com/gustavoverneck/discordia/server/storage"
 )
 
 var upgrader = websocket.Upgrader{
@@ -36,6 +37,12 @@ func main() {
 		log.Fatalf("Falha ao obter a instância do GORM DB.")
 	}
 
+	// Backend dos arquivos enviados (disco local ou S3), escolhido por variáveis de ambiente
+	store, err := storage.FromEnv()
+	if err != nil {
+		log.Fatalf("Falha ao configurar o armazenamento de arquivos: %v", err)
+	}
+
 	// 2. Criação do Router Gin
 	router := gin.Default()
 
@@ -43,24 +50,42 @@ func main() {
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:3000"}, // URL do seu frontend React
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "Accept"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "Accept", "X-Audit-Log-Reason"},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true,
 	}))
 
 	// 4. Instanciação dos Handlers
-	userHandler := handlers.NewUserHandler(gormDB)
-	channelHandler := handlers.NewChannelHandler(gormDB)
-
-	router.Static("/static", "./uploads")
-
-	router.GET("/ws/chat", func(c *gin.Context) { // Rota GET para iniciar a conexão WS
-		handlers.HandleWebSocketChat(c.Writer, c.Request, userHandler.DB) // Passando o DB se necessário para salvar mensagens
-	})
+	// Hub centraliza as conexões WebSocket; precisa rodar em sua própria goroutine.
+	hub := handlers.NewHub()
+	go hub.Run()
+
+	userHandler := handlers.NewUserHandler(gormDB, hub, store)
+
+	serverHandler := handlers.NewServerHandler(gormDB, hub, store)
+	channelHandler := handlers.NewChannelHandler(gormDB, hub, store)
+	auditLogHandler := handlers.NewAuditLogHandler(gormDB)
+	memberHandler := handlers.NewMemberHandler(gormDB, hub, store)
+	inviteHandler := handlers.NewInviteHandler(gormDB, hub)
+	roleHandler := handlers.NewRoleHandler(gormDB)
+	dmHandler := handlers.NewDMHandler(gormDB, hub)
+	relationshipHandler := handlers.NewRelationshipHandler(gormDB, hub)
+	// PresenceHandler publica as mudanças de presença calculadas pelo Hub.
+	presenceHandler := handlers.NewPresenceHandler(gormDB, hub)
+	go presenceHandler.Run()
+	searchHandler := handlers.NewSearchHandler(gormDB, database.MessageSearchAvailable(gormDB))
+	wsHandler := handlers.NewWSHandler(gormDB, hub)
+	uploadHandler := handlers.NewUploadHandler(store)
+
+	// Arquivos enviados: servidos do disco ou redirecionados para o backend (?size=N escolhe a variante das imagens)
+	router.GET("/static/*key", uploadHandler.ServeUpload)
+
+	router.GET("/ws/chat", wsHandler.HandleWebSocketChat) // Rota GET para iniciar a conexão WS
 
 	// 5. Definição das Rotas Públicas
-	router.POST("/register", userHandler.Register) // Rota para registrar usuário
-	router.POST("/login", userHandler.Login)       // Rota para login de usuário
+	router.POST("/register", userHandler.Register)            // Rota para registrar usuário
+	router.POST("/login", userHandler.Login)                  // Rota para login de usuário
+	router.GET("/invites/:code", inviteHandler.PreviewInvite) // Pré-visualização pública de convite
 
 	// 6. Definição das Rotas Protegidas por JWT
 	protected := router.Group("/") // Cria um grupo de rotas
@@ -68,6 +93,14 @@ func main() {
 	{
 		protected.GET("/profile", userHandler.Profile)
 		protected.PUT("/profile", userHandler.UpdateProfile)
+		protected.PUT("/profile/avatar", userHandler.UploadAvatar)
+		protected.DELETE("/profile/avatar", userHandler.DeleteAvatar)
+		protected.PUT("/profile/banner", userHandler.UploadBanner)
+		protected.DELETE("/profile/banner", userHandler.DeleteBanner)
+		protected.GET("/users/:userId", userHandler.GetUserProfile)
+
+		// Ticket de uso único para autenticar o upgrade do WebSocket
+		protected.POST("/ws/ticket", wsHandler.IssueTicket)
 
 		// List user's servers
 		protected.GET("/users/me/servers", userHandler.ListUserServers)
@@ -75,12 +108,73 @@ func main() {
 		// Create server
 		protected.POST("/servers", userHandler.CreateServer)
 
+		// Server management
+		protected.PATCH("/servers/:serverId", serverHandler.UpdateServer)
+		protected.DELETE("/servers/:serverId", serverHandler.DeleteServer)
+		protected.PUT("/servers/:serverId/owner", serverHandler.TransferOwnership)
+		protected.GET("/servers/:serverId/audit-logs", auditLogHandler.ListAuditLogs)
+
+		// Members & bans
+		protected.GET("/servers/:serverId/members", memberHandler.ListMembers)
+		protected.PATCH("/servers/:serverId/members/:userId", memberHandler.UpdateMember)
+		protected.DELETE("/servers/:serverId/members/:userId", memberHandler.KickMember)
+		protected.GET("/servers/:serverId/bans", memberHandler.ListBans)
+		protected.PUT("/servers/:serverId/bans/:userId", memberHandler.BanMember)
+		protected.DELETE("/servers/:serverId/bans/:userId", memberHandler.UnbanMember)
+		protected.PATCH("/users/me/servers/:serverId", memberHandler.UpdateOwnMember)
+		protected.DELETE("/users/me/servers/:serverId", memberHandler.LeaveServer)
+
 		// Chanells routes
 		protected.POST("/servers/:serverId/channels", channelHandler.CreateChannel)
 		protected.GET("/servers/:serverId/channels", channelHandler.ListChannels)
+		protected.PATCH("/servers/:serverId/channels", channelHandler.ReorderChannels)
+		protected.PATCH("/channels/:channelId", channelHandler.UpdateChannel)
+		protected.DELETE("/channels/:channelId", channelHandler.DeleteChannel)
 
 		// Channel Messages
 		protected.GET("/channels/:channelId/messages", channelHandler.ListMessagesInChannel)
+		protected.POST("/channels/:channelId/messages", channelHandler.CreateMessage)
+		protected.PATCH("/channels/:channelId/messages/:messageId", channelHandler.EditMessage)
+		protected.DELETE("/channels/:channelId/messages/:messageId", channelHandler.DeleteMessage)
+		protected.GET("/channels/:channelId/messages/:messageId/edits", channelHandler.ListMessageEdits)
+		protected.GET("/channels/:channelId/messages/:messageId/replies", channelHandler.ListReplies)
+		protected.POST("/channels/:channelId/messages/:messageId/ack", channelHandler.AckMessage)
+		protected.GET("/servers/:serverId/messages/search", searchHandler.SearchMessages)
+
+		// Direct messages
+		protected.GET("/users/me/channels", dmHandler.ListDMChannels)
+		protected.POST("/users/me/channels", dmHandler.CreateDMChannel)
+		protected.PUT("/channels/:channelId/recipients/:userId", dmHandler.AddDMRecipient)
+		protected.DELETE("/channels/:channelId/recipients/:userId", dmHandler.RemoveDMRecipient)
+
+		// Friends & blocks
+		protected.GET("/users/me/relationships", relationshipHandler.ListRelationships)
+		protected.POST("/users/me/relationships", relationshipHandler.SendFriendRequest)
+		protected.PUT("/users/me/relationships/:userId", relationshipHandler.UpdateRelationship)
+		protected.DELETE("/users/me/relationships/:userId", relationshipHandler.DeleteRelationship)
+
+		// Presence
+		protected.GET("/users/me/presences", presenceHandler.ListPresences)
+		protected.PUT("/users/me/status", presenceHandler.UpdateStatus)
+		protected.PUT("/users/me/custom-status", presenceHandler.UpdateCustomStatus)
+		protected.DELETE("/users/me/custom-status", presenceHandler.ClearCustomStatus)
+
+		// Invites
+		protected.POST("/servers/:serverId/invites", inviteHandler.CreateInvite)
+		protected.GET("/servers/:serverId/invites", inviteHandler.ListInvites)
+		protected.POST("/invites/:code", inviteHandler.JoinInvite)
+		protected.DELETE("/invites/:code", inviteHandler.RevokeInvite)
+
+		// Roles & channel permissions
+		protected.GET("/servers/:serverId/roles", roleHandler.ListRoles)
+		protected.POST("/servers/:serverId/roles", roleHandler.CreateRole)
+		protected.PATCH("/servers/:serverId/roles/:roleId", roleHandler.UpdateRole)
+		protected.DELETE("/servers/:serverId/roles/:roleId", roleHandler.DeleteRole)
+		protected.PUT("/servers/:serverId/members/:userId/roles/:roleId", roleHandler.AddMemberRole)
+		protected.DELETE("/servers/:serverId/members/:userId/roles/:roleId", roleHandler.RemoveMemberRole)
+		protected.GET("/channels/:channelId/permissions", roleHandler.ListChannelPermissions)
+		protected.PUT("/channels/:channelId/permissions/:targetType/:targetId", roleHandler.EditChannelPermission)
+		protected.DELETE("/channels/:channelId/permissions/:targetType/:targetId", roleHandler.DeleteChannelPermission)
 	}
 
 	// 7. Inicia o Servidor
@@ -1,6 +1,8 @@
 package models // ou package database, ou outro de sua escolha
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
@@ -14,6 +16,18 @@ type User struct {
 	OwnedServers  []Server  `gorm:"foreignKey:OwnerID"`        // Servidores que este usuário possui
 	MemberServers []*Server `gorm:"many2many:server_members;"` // Servidores dos quais este usuário é membro
 	Messages      []Message `gorm:"foreignKey:AuthorID"`
+
+	// Status personalizado exibido junto da presença. Status guarda o status escolhido
+	// (online, idle, dnd ou invisible); a presença real é calculada pelo gateway.
+	CustomStatus          string     `gorm:"type:varchar(128)"`
+	CustomStatusExpiresAt *time.Time // Nulo = o status personalizado não expira
+
+	// Perfil público
+	DisplayName string `gorm:"type:varchar(32)"`  // Vazio = exibe o username
+	Bio         string `gorm:"type:varchar(190)"` // "Sobre mim"
+	Pronouns    string `gorm:"type:varchar(40)"`
+	BannerURL   string
+	AccentColor string `gorm:"type:varchar(7)"` // #rrggbb; vazio = cor padrão
 }
 
 type Server struct {
@@ -29,25 +43,157 @@ type Server struct {
 
 type Channel struct {
 	gorm.Model
-	ServerID    uint   `gorm:"not null"`
-	Server      Server `gorm:"foreignKey:ServerID"` // Relacionamento Belongs To Server
-	ChannelName string `gorm:"type:varchar(100);not null"`
+	ServerID    *uint   `gorm:"index"`               // Nulo para DMs e DMs em grupo
+	Server      *Server `gorm:"foreignKey:ServerID"` // Relacionamento Belongs To Server
+	ChannelName string  `gorm:"type:varchar(100);not null"`
 	// Para SQLite, o CHECK constraint pode não ser criado por AutoMigrate.
 	// Você pode precisar de DDL customizado ou validação na aplicação.
-	ChannelType string `gorm:"type:varchar(50);not null"` // "TEXT" ou "VOICE"
+	ChannelType string `gorm:"type:varchar(50);not null"` // "TEXT", "VOICE", "CATEGORY", "DM" ou "GROUP_DM"
 	Topic       string
-	Position    int       `gorm:"default:0"`
+	Position    int       `gorm:"default:0"` // Ordem entre os canais com a mesma categoria
+	Bitrate     int       // Canais de voz, em bits/s
+	UserLimit   int       // Canais de voz; 0 = sem limite
 	Messages    []Message `gorm:"foreignKey:ChannelID"`
+	OwnerID     *uint     // Criador de um DM em grupo
+	Recipients  []*User   `gorm:"many2many:channel_recipients;"` // Participantes de DMs e DMs em grupo
+
+	// Categorias agrupam canais de texto e voz de um servidor
+	ParentID          *uint `gorm:"index"` // Categoria do canal; nulo = fora de categorias
+	PermissionsSynced bool  // Sobrescritas copiadas da categoria, acompanhando as alterações dela
 }
 
 type Message struct {
 	gorm.Model
-	ChannelID       uint      `gorm:"not null"`
-	Channel         Channel   `gorm:"foreignKey:ChannelID"` // Relacionamento Belongs To Channel
-	AuthorID        uint      `gorm:"not null"`
-	Author          User      `gorm:"foreignKey:AuthorID"` // Relacionamento Belongs To User
-	Content         string    `gorm:"type:text;not null"`
-	ParentMessageID *uint     // Ponteiro para permitir NULL (respostas a mensagens)
-	ParentMessage   *Message  `gorm:"foreignKey:ParentMessageID;references:ID"`
-	Replies         []Message `gorm:"foreignKey:ParentMessageID;references:ID"`
+	ChannelID       uint       `gorm:"not null"`
+	Channel         Channel    `gorm:"foreignKey:ChannelID"` // Relacionamento Belongs To Channel
+	AuthorID        uint       `gorm:"not null"`
+	Author          User       `gorm:"foreignKey:AuthorID"` // Relacionamento Belongs To User
+	Content         string     `gorm:"type:text;not null"`
+	EditedAt        *time.Time // Nulo enquanto a mensagem nunca foi editada
+	ParentMessageID *uint      // Ponteiro para permitir NULL (respostas a mensagens)
+	ParentMessage   *Message   `gorm:"foreignKey:ParentMessageID;references:ID"`
+	Replies         []Message  `gorm:"foreignKey:ParentMessageID;references:ID"`
+	Edits           []MessageEdit
+	Attachments     []Attachment
+}
+
+// Attachment é um arquivo enviado junto de uma mensagem.
+type Attachment struct {
+	gorm.Model
+	MessageID   uint   `gorm:"not null;index"`
+	Filename    string `gorm:"type:varchar(255);not null"` // Nome original do arquivo
+	URL         string `gorm:"not null"`                   // Caminho público do arquivo salvo
+	ContentType string `gorm:"type:varchar(100);not null"` // Detectado pelo conteúdo
+	Size        int64  `gorm:"not null"`
+	Width       *int   // Dimensões, apenas para imagens
+	Height      *int
+}
+
+// MessageMention registra os usuários mencionados (<@id>) em uma mensagem.
+type MessageMention struct {
+	MessageID uint `gorm:"primaryKey"`
+	UserID    uint `gorm:"primaryKey;index"`
+}
+
+// MessageEdit guarda o conteúdo anterior de uma mensagem a cada edição.
+type MessageEdit struct {
+	gorm.Model
+	MessageID uint   `gorm:"not null;index"`
+	Content   string `gorm:"type:text;not null"` // Conteúdo antes da edição
+}
+
+type Invite struct {
+	gorm.Model
+	Code      string     `gorm:"type:varchar(16);uniqueIndex;not null"`
+	ServerID  uint       `gorm:"not null;index"`
+	Server    Server     `gorm:"foreignKey:ServerID"`
+	CreatorID uint       `gorm:"not null"`
+	Creator   User       `gorm:"foreignKey:CreatorID"`
+	ExpiresAt *time.Time // NULL = nunca expira
+	MaxUses   int        `gorm:"default:0"` // 0 = usos ilimitados
+	Uses      int        `gorm:"default:0"`
+}
+
+// ServerMember é a participação de um usuário em um servidor (tabela de junção
+// server_members de Server.Members e User.MemberServers).
+type ServerMember struct {
+	ServerID     uint       `gorm:"primaryKey"`
+	UserID       uint       `gorm:"primaryKey;index"`
+	Nickname     string     `gorm:"type:varchar(32)"` // Vazio = exibe o nome global
+	AvatarURL    string     // Avatar no servidor; vazio = avatar global
+	JoinedAt     time.Time  `gorm:"autoCreateTime"`
+	TimeoutUntil *time.Time // Até quando o membro está silenciado; nulo = sem castigo
+}
+
+// Ban impede um usuário de voltar ao servidor por convites.
+type Ban struct {
+	ServerID    uint   `gorm:"primaryKey"`
+	UserID      uint   `gorm:"primaryKey;index"`
+	User        User   `gorm:"foreignKey:UserID"`
+	ModeratorID uint   `gorm:"not null"`          // Quem aplicou o banimento
+	Reason      string `gorm:"type:varchar(512)"` // Opcional
+	CreatedAt   time.Time
+}
+
+// AuditLogEntry registra uma ação administrativa feita em um servidor.
+type AuditLogEntry struct {
+	ID         uint      `gorm:"primaryKey"`
+	ServerID   uint      `gorm:"not null;index"`
+	ActorID    uint      `gorm:"not null;index"` // Quem executou a ação
+	Actor      User      `gorm:"foreignKey:ActorID"`
+	ActionType string    `gorm:"type:varchar(50);not null;index"`
+	TargetType string    `gorm:"type:varchar(20)"` // server, channel, role, user, invite ou message
+	TargetID   uint      // Zero quando a ação não tem alvo
+	Changes    string    `gorm:"type:text"` // JSON: lista de {key, old, new}
+	Options    string    `gorm:"type:text"` // JSON com dados extras da ação; vazio se não houver
+	Reason     string    `gorm:"type:varchar(512)"`
+	CreatedAt  time.Time `gorm:"index"`
+}
+
+type Role struct {
+	gorm.Model
+	ServerID    uint   `gorm:"not null;index"`
+	Server      Server `gorm:"foreignKey:ServerID"`
+	Name        string `gorm:"type:varchar(100);not null"`
+	Color       int    `gorm:"default:0"`     // Cor RGB (0xRRGGBB)
+	Position    int    `gorm:"default:0"`     // Quanto maior, mais alto na hierarquia; @everyone fica em 0
+	Permissions int64  `gorm:"default:0"`     // Bitfield de permissões
+	IsDefault   bool   `gorm:"default:false"` // Cargo @everyone do servidor
+}
+
+// MemberRole associa um membro de servidor a um cargo.
+type MemberRole struct {
+	UserID   uint `gorm:"primaryKey"`
+	RoleID   uint `gorm:"primaryKey"`
+	ServerID uint `gorm:"not null;index"`
+}
+
+// PermissionOverwrite sobrescreve, em um canal, as permissões de um cargo ou de um membro.
+type PermissionOverwrite struct {
+	gorm.Model
+	ChannelID  uint   `gorm:"not null;index"`
+	TargetType string `gorm:"type:varchar(10);not null"` // "role" ou "member"
+	TargetID   uint   `gorm:"not null"`                  // ID do cargo ou do usuário
+	Allow      int64  `gorm:"default:0"`
+	Deny       int64  `gorm:"default:0"`
+}
+
+// ReadState guarda até onde o usuário leu um canal e quantas menções a ele há depois disso.
+type ReadState struct {
+	UserID        uint `gorm:"primaryKey"`
+	ChannelID     uint `gorm:"primaryKey;index"`
+	LastMessageID uint `gorm:"default:0"` // Última mensagem confirmada como lida (0 = nenhuma)
+	MentionCount  int  `gorm:"default:0"`
+	UpdatedAt     time.Time
+}
+
+// Relationship guarda a relação de UserID com TargetID, do ponto de vista de UserID.
+// Um pedido de amizade gera duas linhas (PENDING_OUTGOING e PENDING_INCOMING);
+// um bloqueio existe apenas na linha de quem bloqueou.
+type Relationship struct {
+	UserID    uint      `gorm:"primaryKey"`
+	TargetID  uint      `gorm:"primaryKey;index"`
+	Target    User      `gorm:"foreignKey:TargetID"`
+	Type      string    `gorm:"type:varchar(20);not null"` // "FRIEND", "BLOCKED", "PENDING_INCOMING" ou "PENDING_OUTGOING"
+	CreatedAt time.Time // Momento da última mudança de tipo
 }
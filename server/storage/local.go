@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Local guarda os objetos em um diretório do disco, entregue pelo próprio
+// servidor HTTP em BaseURL.
+type Local struct {
+	Root    string // Diretório raiz dos objetos
+	BaseURL string // Prefixo das URLs públicas, ex: "/static/"
+}
+
+// NewLocal é o construtor para Local.
+func NewLocal(root, baseURL string) *Local {
+	return &Local{Root: root, BaseURL: baseURL}
+}
+
+func (l *Local) path(key string) string {
+	return filepath.Join(l.Root, filepath.FromSlash(key))
+}
+
+// Put grava o objeto em um arquivo temporário e o renomeia ao final, para que
+// leitores nunca vejam um arquivo pela metade.
+func (l *Local) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	if err := validKey(key); err != nil {
+		return err
+	}
+	path := l.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// Delete remove o arquivo do objeto, se existir.
+func (l *Local) Delete(ctx context.Context, key string) error {
+	if err := validKey(key); err != nil {
+		return err
+	}
+	if err := os.Remove(l.path(key)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// URL devolve o endereço público do arquivo; no disco local ele não expira.
+func (l *Local) URL(ctx context.Context, key string) (string, error) {
+	if err := validKey(key); err != nil {
+		return "", err
+	}
+	return l.BaseURL + key, nil
+}
+
+// Path devolve o caminho no disco do arquivo de um objeto.
+func (l *Local) Path(key string) (string, error) {
+	if err := validKey(key); err != nil {
+		return "", err
+	}
+	return l.path(key), nil
+}
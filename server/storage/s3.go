@@ -0,0 +1,287 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultS3Region         = "us-east-1"
+	defaultPresignExpiry    = 15 * time.Minute
+	maxPresignExpirySeconds = 7 * 24 * 60 * 60 // Limite do SigV4 para links pré-assinados
+	unsignedPayload         = "UNSIGNED-PAYLOAD"
+	sigV4Algorithm          = "AWS4-HMAC-SHA256"
+	sigV4TimeFormat         = "20060102T150405Z"
+	sigV4DateFormat         = "20060102"
+)
+
+// S3Config configura o backend S3. Os objetos são endereçados no estilo de
+// caminho (endpoint/bucket/chave), suportado pela AWS e pelo MinIO.
+type S3Config struct {
+	Endpoint        string // ex: http://localhost:9000; vazio = https://s3.<região>.amazonaws.com
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	PublicURL       string        // Base pública do bucket; vazio = URLs pré-assinadas
+	PresignExpiry   time.Duration // Validade das URLs pré-assinadas
+}
+
+// S3 guarda os objetos em um bucket compatível com S3, assinando as
+// requisições com AWS Signature Version 4.
+type S3 struct {
+	cfg      S3Config
+	endpoint *url.URL
+	client   *http.Client
+	now      func() time.Time
+}
+
+// NewS3 é o construtor para S3.
+func NewS3(cfg S3Config) (*S3, error) {
+	if cfg.Bucket == "" || cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, errors.New("storage: S3 exige bucket, access key e secret key")
+	}
+	if cfg.Region == "" {
+		cfg.Region = defaultS3Region
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "https://s3." + cfg.Region + ".amazonaws.com"
+	}
+	if cfg.PresignExpiry == 0 {
+		cfg.PresignExpiry = defaultPresignExpiry
+	}
+	endpoint, err := url.Parse(strings.TrimRight(cfg.Endpoint, "/"))
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil, fmt.Errorf("storage: endpoint S3 inválido: %q", cfg.Endpoint)
+	}
+	return &S3{
+		cfg:      cfg,
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: 5 * time.Minute},
+		now:      time.Now,
+	}, nil
+}
+
+// objectURL monta a URL do objeto no estilo de caminho.
+func (s *S3) objectURL(key string) *url.URL {
+	u := *s.endpoint
+	u.Path = u.Path + "/" + s.cfg.Bucket + "/" + key
+	u.RawPath = escapePath(u.Path)
+	return &u
+}
+
+// Put envia o objeto com PUT; size precisa ser o tamanho exato do corpo. O corpo
+// não entra na assinatura (UNSIGNED-PAYLOAD), o que permite enviá-lo sem lê-lo duas vezes.
+func (s *S3) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	if err := validKey(key); err != nil {
+		return err
+	}
+	if size == 0 {
+		body = http.NoBody // Sem isso o corpo vazio seria enviado em chunks, que o S3 recusa
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.objectURL(key).String(), body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return s.do(req, http.StatusOK)
+}
+
+// Delete remove o objeto. O S3 responde 204 mesmo para chaves inexistentes.
+func (s *S3) Delete(ctx context.Context, key string) error {
+	if err := validKey(key); err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, s.objectURL(key).String(), nil)
+	if err != nil {
+		return err
+	}
+	return s.do(req, http.StatusNoContent, http.StatusOK, http.StatusNotFound)
+}
+
+// URL devolve PublicURL/chave quando o bucket é público ou, caso contrário,
+// uma URL GET pré-assinada válida por PresignExpiry.
+func (s *S3) URL(ctx context.Context, key string) (string, error) {
+	if err := validKey(key); err != nil {
+		return "", err
+	}
+	if s.cfg.PublicURL != "" {
+		return strings.TrimRight(s.cfg.PublicURL, "/") + "/" + escapePath(key), nil
+	}
+	return s.presign(http.MethodGet, s.objectURL(key), s.cfg.PresignExpiry), nil
+}
+
+// do assina e executa a requisição, tratando como erro os status não esperados.
+func (s *S3) do(req *http.Request, expected ...int) error {
+	s.sign(req)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	for _, status := range expected {
+		if resp.StatusCode == status {
+			io.Copy(io.Discard, resp.Body)
+			return nil
+		}
+	}
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("storage: %s %s: %s: %s", req.Method, req.URL.Path, resp.Status, strings.TrimSpace(string(detail)))
+}
+
+// sign adiciona os cabeçalhos de autenticação SigV4 à requisição. O corpo não
+// é assinado (UNSIGNED-PAYLOAD), a menos que X-Amz-Content-Sha256 já traga o hash dele.
+func (s *S3) sign(req *http.Request) {
+	now := s.now().UTC()
+	req.Header.Set("X-Amz-Date", now.Format(sigV4TimeFormat))
+	payloadHash := req.Header.Get("X-Amz-Content-Sha256")
+	if payloadHash == "" {
+		payloadHash = unsignedPayload
+		req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.Join(values, ",")
+	}
+	if req.ContentLength > 0 {
+		headers["content-length"] = strconv.FormatInt(req.ContentLength, 10)
+	}
+	canonicalHeaders, signedHeaders := canonicalizeHeaders(headers)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := s.scope(now)
+	signature := s.signature(now, scope, canonicalRequest)
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		sigV4Algorithm, s.cfg.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// presign devolve a URL com a assinatura SigV4 nos parâmetros da query.
+func (s *S3) presign(method string, u *url.URL, expires time.Duration) string {
+	now := s.now().UTC()
+	scope := s.scope(now)
+	query := u.Query()
+	query.Set("X-Amz-Algorithm", sigV4Algorithm)
+	query.Set("X-Amz-Credential", s.cfg.AccessKeyID+"/"+scope)
+	query.Set("X-Amz-Date", now.Format(sigV4TimeFormat))
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expires.Seconds())))
+	query.Set("X-Amz-SignedHeaders", "host")
+
+	canonicalRequest := strings.Join([]string{
+		method,
+		u.EscapedPath(),
+		canonicalQuery(query),
+		"host:" + u.Host + "\n",
+		"host",
+		unsignedPayload,
+	}, "\n")
+	query.Set("X-Amz-Signature", s.signature(now, scope, canonicalRequest))
+
+	signed := *u
+	signed.RawQuery = canonicalQuery(query)
+	return signed.String()
+}
+
+func (s *S3) scope(now time.Time) string {
+	return now.Format(sigV4DateFormat) + "/" + s.cfg.Region + "/s3/aws4_request"
+}
+
+// signature calcula a assinatura da requisição canônica com a chave derivada do dia.
+func (s *S3) signature(now time.Time, scope, canonicalRequest string) string {
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		sigV4Algorithm,
+		now.Format(sigV4TimeFormat),
+		scope,
+		hex.EncodeToString(hash[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.cfg.SecretAccessKey), now.Format(sigV4DateFormat))
+	key = hmacSHA256(key, s.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalizeHeaders devolve os cabeçalhos ordenados no formato do SigV4 e a lista dos assinados.
+func canonicalizeHeaders(headers map[string]string) (canonical, signed string) {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	return b.String(), strings.Join(names, ";")
+}
+
+// canonicalQuery ordena os parâmetros e os codifica como o SigV4 exige (espaço = %20).
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, key := range keys {
+		values := append([]string(nil), query[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			parts = append(parts, uriEncode(key, true)+"="+uriEncode(value, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// escapePath codifica cada segmento do caminho, mantendo as barras.
+func escapePath(path string) string {
+	return uriEncode(path, false)
+}
+
+// uriEncode codifica tudo exceto os caracteres não reservados (RFC 3986);
+// a barra só é codificada quando encodeSlash é verdadeiro.
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,77 @@
+// Package storage guarda os arquivos enviados pelos usuários (anexos, ícones,
+// avatares) em um backend configurável: o disco local ou um serviço compatível
+// com S3 (AWS S3, MinIO, etc.), o que permite rodar várias instâncias do servidor.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strconv"
+	"time"
+)
+
+// ErrInvalidKey indica uma chave vazia, absoluta ou com "..".
+var ErrInvalidKey = errors.New("storage: chave inválida")
+
+// Storage é um repositório de objetos endereçados por chave, ex: "attachments/<uuid>.png".
+type Storage interface {
+	// Put grava o objeto, substituindo um existente com a mesma chave.
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	// Delete remove o objeto. Remover uma chave inexistente não é erro.
+	Delete(ctx context.Context, key string) error
+	// URL devolve o endereço para leitura do objeto, que pode ser temporário (pré-assinado).
+	URL(ctx context.Context, key string) (string, error)
+}
+
+// validKey garante que a chave não escapa do diretório/bucket do backend.
+func validKey(key string) error {
+	if key == "" || key == "." || !fs.ValidPath(key) {
+		return ErrInvalidKey
+	}
+	return nil
+}
+
+// FromEnv cria o backend escolhido pelas variáveis de ambiente:
+//
+//	STORAGE_BACKEND          local (padrão) ou s3
+//	UPLOADS_DIR              diretório do backend local (padrão ./uploads)
+//	S3_ENDPOINT              ex: http://localhost:9000 (padrão: AWS na região configurada)
+//	S3_REGION                padrão us-east-1
+//	S3_BUCKET                obrigatório para s3
+//	S3_ACCESS_KEY_ID         obrigatório para s3
+//	S3_SECRET_ACCESS_KEY     obrigatório para s3
+//	S3_PUBLIC_URL            opcional; base pública do bucket. Sem ela, URL devolve links pré-assinados
+//	S3_PRESIGN_EXPIRY        validade dos links pré-assinados em segundos (padrão 900)
+func FromEnv() (Storage, error) {
+	switch backend := os.Getenv("STORAGE_BACKEND"); backend {
+	case "", "local":
+		root := os.Getenv("UPLOADS_DIR")
+		if root == "" {
+			root = "./uploads"
+		}
+		return NewLocal(root, "/static/"), nil
+	case "s3":
+		cfg := S3Config{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			Region:          os.Getenv("S3_REGION"),
+			Bucket:          os.Getenv("S3_BUCKET"),
+			AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+			PublicURL:       os.Getenv("S3_PUBLIC_URL"),
+		}
+		if raw := os.Getenv("S3_PRESIGN_EXPIRY"); raw != "" {
+			seconds, err := strconv.Atoi(raw)
+			if err != nil || seconds < 1 || seconds > maxPresignExpirySeconds {
+				return nil, fmt.Errorf("storage: S3_PRESIGN_EXPIRY inválido: %q", raw)
+			}
+			cfg.PresignExpiry = time.Duration(seconds) * time.Second
+		}
+		return NewS3(cfg)
+	default:
+		return nil, fmt.Errorf("storage: STORAGE_BACKEND desconhecido: %q", backend)
+	}
+}
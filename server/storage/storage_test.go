@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidKey(t *testing.T) {
+	tests := []struct {
+		key   string
+		valid bool
+	}{
+		{"attachments/a.png", true},
+		{"avatars/1/foto.png", true},
+		{"a..b.png", true},
+		{"", false},
+		{".", false},
+		{"..", false},
+		{"../segredo", false},
+		{"attachments/../../segredo", false},
+		{"attachments/..", false},
+		{"/etc/passwd", false},
+		{"attachments//a.png", false},
+		{"attachments/", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			err := validKey(tt.key)
+			if tt.valid && err != nil {
+				t.Errorf("validKey(%q) = %v, esperado nil", tt.key, err)
+			}
+			if !tt.valid && err != ErrInvalidKey {
+				t.Errorf("validKey(%q) = %v, esperado ErrInvalidKey", tt.key, err)
+			}
+		})
+	}
+}
+
+func TestLocalRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	l := NewLocal(root, "/static/")
+	key := "attachments/sub/a.txt"
+
+	if err := l.Put(ctx, key, strings.NewReader("primeira"), 8, "text/plain"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	// Put substitui o objeto existente
+	if err := l.Put(ctx, key, strings.NewReader("segunda versão"), 15, "text/plain"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	path, err := l.Path(key)
+	if err != nil {
+		t.Fatalf("Path: %v", err)
+	}
+	if want := filepath.Join(root, "attachments", "sub", "a.txt"); path != want {
+		t.Errorf("Path = %q, esperado %q", path, want)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "segunda versão" {
+		t.Errorf("conteúdo = %q", data)
+	}
+	// Nenhum arquivo temporário fica para trás
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("arquivos no diretório = %d, esperado 1", len(entries))
+	}
+
+	url, err := l.URL(ctx, key)
+	if err != nil || url != "/static/attachments/sub/a.txt" {
+		t.Errorf("URL = %q, %v", url, err)
+	}
+
+	if err := l.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("arquivo ainda existe: %v", err)
+	}
+	// Remover uma chave inexistente não é erro
+	if err := l.Delete(ctx, key); err != nil {
+		t.Errorf("Delete repetido: %v", err)
+	}
+}
+
+func TestLocalRejectsInvalidKeys(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	l := NewLocal(filepath.Join(root, "uploads"), "/static/")
+
+	if err := l.Put(ctx, "../fora.txt", strings.NewReader("x"), 1, ""); err != ErrInvalidKey {
+		t.Errorf("Put = %v, esperado ErrInvalidKey", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "fora.txt")); !os.IsNotExist(err) {
+		t.Errorf("arquivo gravado fora da raiz: %v", err)
+	}
+	if err := l.Delete(ctx, ".."); err != ErrInvalidKey {
+		t.Errorf("Delete = %v, esperado ErrInvalidKey", err)
+	}
+	if _, err := l.URL(ctx, "a/../../b"); err != ErrInvalidKey {
+		t.Errorf("URL = %v, esperado ErrInvalidKey", err)
+	}
+	if _, err := l.Path("/etc/passwd"); err != ErrInvalidKey {
+		t.Errorf("Path = %v, esperado ErrInvalidKey", err)
+	}
+}